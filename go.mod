@@ -2,10 +2,16 @@ module github.com/J-Bockhofer/goe2e
 
 go 1.22.3
 
-require github.com/stretchr/testify v1.9.0
+require (
+	github.com/andybalholm/brotli v1.2.0
+	github.com/stretchr/testify v1.9.0
+	golang.org/x/net v0.35.0
+	google.golang.org/protobuf v1.36.6
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
+	golang.org/x/text v0.22.0 // indirect
 )
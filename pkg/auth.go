@@ -0,0 +1,246 @@
+package goe2e
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// WithBasicAuth sets the Authorization header for HTTP Basic authentication.
+func WithBasicAuth(username, password string) RequestModifier {
+	return func(r *http.Request) error {
+		DefaultSecrets.Add(password)
+		r.SetBasicAuth(username, password)
+		return nil
+	}
+}
+
+// WithBearerToken sets the Authorization header to "Bearer <token>".
+func WithBearerToken(token string) RequestModifier {
+	return func(r *http.Request) error {
+		DefaultSecrets.Add(token)
+		r.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	}
+}
+
+// WithAPIKeyHeader sends an api key in the given header. The header is marked as sensitive in the DefaultSecrets.
+func WithAPIKeyHeader(header, key string) RequestModifier {
+	return func(r *http.Request) error {
+		DefaultSecrets.AddHeader(header)
+		DefaultSecrets.Add(key)
+		r.Header.Set(header, key)
+		return nil
+	}
+}
+
+// WithAPIKeyQuery sends an api key as query parameter of the request url.
+func WithAPIKeyQuery(param, key string) RequestModifier {
+	return func(r *http.Request) error {
+		DefaultSecrets.Add(key)
+		q := r.URL.Query()
+		q.Set(param, key)
+		r.URL.RawQuery = q.Encode()
+		return nil
+	}
+}
+
+// TokenExtractor reads a token, like an access token or session id, from a response.
+type TokenExtractor func(*RequestHandler) (string, error)
+
+// TokenFromJSON extracts the token from the JSON response body, searching nested objects like ValueInMapByKey.
+func TokenFromJSON(key string) TokenExtractor {
+	return func(rh *RequestHandler) (string, error) {
+		body, err := bodyJSONToMap(rh.ResponseBody)
+		if err != nil {
+			return "", fmt.Errorf("token extraction failed - json.Unmarshal: %s", err.Error())
+		}
+		token, ok := ValueInMapByKey(key, body).(string)
+		if !ok || token == "" {
+			return "", fmt.Errorf("token extraction failed: no string %s in response body", key)
+		}
+		return token, nil
+	}
+}
+
+// TokenFromHeader extracts the token from a response header.
+func TokenFromHeader(header string) TokenExtractor {
+	return func(rh *RequestHandler) (string, error) {
+		token := rh.Response.Header.Get(header)
+		if token == "" {
+			return "", fmt.Errorf("token extraction failed: no header %s in response", header)
+		}
+		return token, nil
+	}
+}
+
+// TokenFromCookie extracts the token from a cookie set by the response.
+func TokenFromCookie(name string) TokenExtractor {
+	return func(rh *RequestHandler) (string, error) {
+		for _, c := range rh.Response.Cookies() {
+			if c.Name == name && c.Value != "" {
+				return c.Value, nil
+			}
+		}
+		return "", fmt.Errorf("token extraction failed: no cookie %s in response", name)
+	}
+}
+
+// TokenApplier attaches a token to a request.
+type TokenApplier func(r *http.Request, token string)
+
+// ApplyBearer sends the token as "Authorization: Bearer <token>".
+func ApplyBearer() TokenApplier {
+	return func(r *http.Request, token string) {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+}
+
+// ApplyHeader sends the token as value of the given header.
+func ApplyHeader(header string) TokenApplier {
+	return func(r *http.Request, token string) {
+		r.Header.Set(header, token)
+	}
+}
+
+// ApplyCookie sends the token as the value of the named cookie, replacing a cookie of the same name.
+func ApplyCookie(name string) TokenApplier {
+	return func(r *http.Request, token string) {
+		cookies := r.Cookies()
+		r.Header.Del("Cookie")
+		for _, c := range cookies {
+			if c.Name != name {
+				r.AddCookie(c)
+			}
+		}
+		r.AddCookie(&http.Cookie{Name: name, Value: token})
+	}
+}
+
+// Login performs a login request, caches the obtained token in an Env and applies it to subsequent requests.
+// Use it with WithLogin to also log in again when a request is answered with 401 Unauthorized.
+type Login struct {
+	// Config is the login request. Its test statements are not run.
+	Config *TestConfig
+	// Extract reads the token from the login response.
+	Extract TokenExtractor
+	// Apply attaches the token to requests, ApplyBearer by default.
+	Apply TokenApplier
+	// Env caches the token as secret under Key, so it can be shared and is redacted from output.
+	Env *Env
+	Key string
+
+	mu sync.Mutex
+}
+
+// NewLogin creates a Login that sends the token extracted from the response of config as bearer token.
+func NewLogin(config *TestConfig, extract TokenExtractor, env *Env, key string) *Login {
+	return &Login{
+		Config:  config,
+		Extract: extract,
+		Apply:   ApplyBearer(),
+		Env:     env,
+		Key:     key,
+	}
+}
+
+// Token returns the cached token, logging in if there is none yet.
+func (l *Login) Token() (string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if token, err := l.Env.String(l.Key); err == nil && token != "" {
+		return token, nil
+	}
+	return l.login()
+}
+
+// Refresh logs in again, replacing the cached token.
+func (l *Login) Refresh() (string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.login()
+}
+
+func (l *Login) login() (string, error) {
+	rh, err := RunConfig(l.Config)
+	if err != nil {
+		return "", fmt.Errorf("login failed: %w", err)
+	}
+	if rh.Response.StatusCode >= http.StatusBadRequest {
+		return "", fmt.Errorf("login failed: %s responded with status %d", l.Config.Name, rh.Response.StatusCode)
+	}
+	token, err := l.Extract(rh)
+	if err != nil {
+		return "", fmt.Errorf("login failed: %w", err)
+	}
+	l.Env.SetSecret(l.Key, token)
+	return token, nil
+}
+
+// RequestModifier attaches the token to the request, logging in if there is no cached token yet.
+func (l *Login) RequestModifier() RequestModifier {
+	return func(r *http.Request) error {
+		token, err := l.Token()
+		if err != nil {
+			return err
+		}
+		l.Apply(r, token)
+		return nil
+	}
+}
+
+// WithLogin attaches the token of the Login to every request sent by the handler.
+// If a request is answered with 401 Unauthorized, it logs in again and retries the request once with the new token.
+func WithLogin(l *Login) RequestHandlerOption {
+	return WithRoundTripper(func(next http.RoundTripper) http.RoundTripper {
+		return &tokenTransport{
+			next:    next,
+			token:   l.Token,
+			refresh: l.Refresh,
+			apply:   l.Apply,
+		}
+	})
+}
+
+// tokenTransport attaches a token to each request and refreshes it on 401 Unauthorized.
+type tokenTransport struct {
+	next    http.RoundTripper
+	token   func() (string, error)
+	refresh func() (string, error)
+	apply   TokenApplier
+}
+
+func (tt *tokenTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	token, err := tt.token()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := tt.next.RoundTrip(tt.withToken(r, token))
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	// the body of the first attempt is consumed, retrying is only possible if it can be recreated
+	if r.Body != nil && r.Body != http.NoBody && r.GetBody == nil {
+		return resp, nil
+	}
+	resp.Body.Close()
+	token, err = tt.refresh()
+	if err != nil {
+		return nil, err
+	}
+	retry := tt.withToken(r, token)
+	if r.GetBody != nil {
+		retry.Body, err = r.GetBody()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return tt.next.RoundTrip(retry)
+}
+
+// withToken clones the request, as a http.RoundTripper must not modify the request it was given.
+func (tt *tokenTransport) withToken(r *http.Request, token string) *http.Request {
+	c := r.Clone(r.Context())
+	tt.apply(c, token)
+	return c
+}
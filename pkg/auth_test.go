@@ -0,0 +1,172 @@
+package goe2e_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	goe2e "github.com/J-Bockhofer/goe2e/pkg"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthModifiers(t *testing.T) {
+	testCases := []struct {
+		name   string
+		mod    goe2e.RequestModifier
+		check  func(*http.Request) string
+		expect string
+	}{
+		{"Basic", goe2e.WithBasicAuth("john", "secret"),
+			func(r *http.Request) string { return r.Header.Get("Authorization") }, "Basic am9objpzZWNyZXQ="},
+		{"Bearer", goe2e.WithBearerToken("tok123"),
+			func(r *http.Request) string { return r.Header.Get("Authorization") }, "Bearer tok123"},
+		{"API key header", goe2e.WithAPIKeyHeader("X-Key", "key123"),
+			func(r *http.Request) string { return r.Header.Get("X-Key") }, "key123"},
+		{"API key query", goe2e.WithAPIKeyQuery("api_key", "key 456"),
+			func(r *http.Request) string { return r.URL.RawQuery }, "api_key=key+456&page=1"},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, err := goe2e.NewSpec(goe2e.WithUrl("https://example.com/persons?page=1"))
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.NoError(t, tt.mod(spec.Request))
+			assert.Equal(t, tt.expect, tt.check(spec.Request))
+		})
+	}
+	assert.True(t, goe2e.DefaultSecrets.IsSensitiveHeader("X-Key"))
+}
+
+func TestApplyCookie(t *testing.T) {
+	r, _ := http.NewRequest(http.MethodGet, "https://example.com", nil)
+	r.AddCookie(&http.Cookie{Name: "theme", Value: "dark"})
+	r.AddCookie(&http.Cookie{Name: "session", Value: "old"})
+	goe2e.ApplyCookie("session")(r, "new")
+	assert.Equal(t, "theme=dark; session=new", r.Header.Get("Cookie"))
+}
+
+// authServer issues a new token on every login and only accepts the latest one.
+type authServer struct {
+	logins int
+	token  string
+}
+
+func (s *authServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/login":
+		s.logins++
+		s.token = fmt.Sprintf("token-%d", s.logins)
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: s.token})
+		w.Header().Set("X-Token", s.token)
+		fmt.Fprintf(w, `{"data":{"access_token":%q}}`, s.token)
+	case "/echo":
+		if r.Header.Get("Authorization") != "Bearer "+s.token {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body)
+	}
+}
+
+func TestTokenExtractors(t *testing.T) {
+	srv := httptest.NewServer(&authServer{})
+	defer srv.Close()
+	rh, err := goe2e.RunConfig(&goe2e.TestConfig{
+		Name:     "login",
+		SpecOpts: []goe2e.SpecOption{goe2e.WithUrl(srv.URL + "/login")},
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	testCases := []struct {
+		name    string
+		extract goe2e.TokenExtractor
+		err     bool
+	}{
+		{"JSON", goe2e.TokenFromJSON("access_token"), false},
+		{"Header", goe2e.TokenFromHeader("X-Token"), false},
+		{"Cookie", goe2e.TokenFromCookie("session"), false},
+		{"JSON missing", goe2e.TokenFromJSON("refresh_token"), true},
+		{"Header missing", goe2e.TokenFromHeader("X-Missing"), true},
+		{"Cookie missing", goe2e.TokenFromCookie("missing"), true},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := tt.extract(rh)
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, "token-1", token)
+		})
+	}
+}
+
+func TestLogin(t *testing.T) {
+	srv := &authServer{}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	env := goe2e.NewEnv()
+	login := goe2e.NewLogin(&goe2e.TestConfig{
+		Name:     "POST /login",
+		SpecOpts: []goe2e.SpecOption{goe2e.WithMethod(http.MethodPost), goe2e.WithUrl(ts.URL + "/login")},
+	}, goe2e.TokenFromJSON("access_token"), env, "token")
+
+	tc := &goe2e.TestConfig{
+		Name: "POST /echo",
+		SpecOpts: []goe2e.SpecOption{
+			goe2e.WithMethod(http.MethodPost),
+			goe2e.WithUrl(ts.URL + "/echo"),
+			goe2e.WithBody([]byte("hello")),
+		},
+		HandlerOpts: []goe2e.RequestHandlerOption{goe2e.WithLogin(login)},
+		PostTestStatements: []goe2e.TestStatement{
+			{Description: "status 200", Statement: goe2e.TestStatusCode(http.StatusOK)},
+			{Description: "body sent", Statement: func(t *testing.T, rh *goe2e.RequestHandler) {
+				assert.Equal(t, "hello", string(rh.ResponseBody))
+			}},
+		},
+	}
+
+	goe2e.TestRequest(t, tc)
+	assert.Equal(t, 1, srv.logins)
+	token, _ := env.String("token")
+	assert.Equal(t, "token-1", token)
+	assert.True(t, env.IsSecret("token"))
+
+	// the cached token is reused
+	goe2e.TestRequest(t, tc)
+	assert.Equal(t, 1, srv.logins)
+
+	// an invalidated token leads to a new login and a retry including the body
+	srv.token = "revoked"
+	goe2e.TestRequest(t, tc)
+	assert.Equal(t, 2, srv.logins)
+	token, _ = env.String("token")
+	assert.Equal(t, "token-2", token)
+
+	t.Run("RequestModifier", func(t *testing.T) {
+		spec, err := goe2e.NewSpec(goe2e.WithUrl(ts.URL + "/echo"))
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.NoError(t, login.RequestModifier()(spec.Request))
+		assert.Equal(t, "Bearer token-2", spec.Request.Header.Get("Authorization"))
+	})
+
+	t.Run("Failed login", func(t *testing.T) {
+		failing := goe2e.NewLogin(&goe2e.TestConfig{
+			Name:     "GET /missing",
+			SpecOpts: []goe2e.SpecOption{goe2e.WithUrl(ts.URL + "/missing")},
+		}, goe2e.TokenFromJSON("access_token"), goe2e.NewEnv(), "token")
+		_, err := failing.Token()
+		assert.Error(t, err)
+	})
+}
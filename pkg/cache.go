@@ -0,0 +1,263 @@
+package goe2e
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Validators are the headers of a response a client uses to revalidate its cached copy with a conditional request.
+type Validators struct {
+	ETag         string
+	LastModified string
+}
+
+// ValidatorsFromResponse captures the ETag and Last-Modified headers of a response.
+func ValidatorsFromResponse(resp *http.Response) Validators {
+	return Validators{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}
+}
+
+// WithIfNoneMatch sets the If-None-Match header to make the request conditional on the entity tag.
+func WithIfNoneMatch(etag string) RequestModifier {
+	return func(r *http.Request) error {
+		r.Header.Set("If-None-Match", etag)
+		return nil
+	}
+}
+
+// WithIfModifiedSince sets the If-Modified-Since header to make the request conditional on the modification time.
+func WithIfModifiedSince(t time.Time) RequestModifier {
+	return func(r *http.Request) error {
+		r.Header.Set("If-Modified-Since", t.UTC().Format(http.TimeFormat))
+		return nil
+	}
+}
+
+// WithValidators makes the request conditional on all validators that are set, like a cache revalidating its copy.
+func WithValidators(v Validators) RequestModifier {
+	return func(r *http.Request) error {
+		if v.ETag != "" {
+			r.Header.Set("If-None-Match", v.ETag)
+		}
+		if v.LastModified != "" {
+			r.Header.Set("If-Modified-Since", v.LastModified)
+		}
+		return nil
+	}
+}
+
+// Revalidate replays the request of the handler as conditional request with the validators of its response.
+// The returned handler holds the new response and shares the client and options of the handler.
+func (rh *RequestHandler) Revalidate() (*RequestHandler, error) {
+	if rh.Response == nil {
+		return nil, fmt.Errorf("no response gathered before revalidating it")
+	}
+	v := ValidatorsFromResponse(rh.Response)
+	if v.ETag == "" && v.LastModified == "" {
+		return nil, fmt.Errorf("response has neither ETag nor Last-Modified to revalidate with")
+	}
+	r := rh.spec.Request.Clone(rh.spec.Request.Context())
+	if r.GetBody != nil {
+		body, err := r.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		r.Body = body
+	}
+	conditional := rh.derive(r)
+	if err := conditional.ModifyRequest(WithValidators(v)); err != nil {
+		return nil, err
+	}
+	if err := conditional.RunRequest(); err != nil {
+		return nil, err
+	}
+	return conditional, nil
+}
+
+// TestNotModified revalidates the response and asserts that the server answers with 304 Not Modified without a body.
+// The 304 response has to repeat the ETag, Cache-Control and Vary headers of the original response.
+func TestNotModified() func(*testing.T, *RequestHandler) {
+	return func(t *testing.T, rh *RequestHandler) {
+		conditional, err := rh.Revalidate()
+		if err != nil {
+			assert.Fail(t, err.Error())
+			return
+		}
+		if !assert.Equal(t, http.StatusNotModified, conditional.Response.StatusCode, "status of conditional request") {
+			return
+		}
+		assert.Empty(t, conditional.ResponseBody, "304 response has a body")
+		for _, name := range []string{"ETag", "Cache-Control", "Vary"} {
+			if expected := rh.Response.Header.Get(name); expected != "" {
+				assert.Equal(t, expected, conditional.Response.Header.Get(name), "%s of 304 response", name)
+			}
+		}
+	}
+}
+
+// TestModified revalidates the response and asserts that the server sends the full resource again, e.g. after changing it.
+func TestModified() func(*testing.T, *RequestHandler) {
+	return func(t *testing.T, rh *RequestHandler) {
+		conditional, err := rh.Revalidate()
+		if err != nil {
+			assert.Fail(t, err.Error())
+			return
+		}
+		assert.Equal(t, http.StatusOK, conditional.Response.StatusCode, "status of conditional request")
+	}
+}
+
+// CacheControl holds the directives of a Cache-Control header, directives without argument have an empty value.
+type CacheControl map[string]string
+
+// ParseCacheControl parses the directives of Cache-Control header values, directive names are lowercased.
+func ParseCacheControl(values ...string) CacheControl {
+	cc := CacheControl{}
+	for _, v := range values {
+		for _, directive := range strings.Split(v, ",") {
+			name, arg, _ := strings.Cut(strings.TrimSpace(directive), "=")
+			if name == "" {
+				continue
+			}
+			cc[strings.ToLower(strings.TrimSpace(name))] = strings.Trim(strings.TrimSpace(arg), `"`)
+		}
+	}
+	return cc
+}
+
+// String renders the directives sorted by name.
+func (cc CacheControl) String() string {
+	directives := make([]string, 0, len(cc))
+	for name, arg := range cc {
+		if arg != "" {
+			name += "=" + arg
+		}
+		directives = append(directives, name)
+	}
+	sort.Strings(directives)
+	return strings.Join(directives, ", ")
+}
+
+// CacheControlCheck checks a single property of the Cache-Control header.
+type CacheControlCheck func(CacheControl) error
+
+// CacheDirective checks that the directive, like no-store or public, is present.
+func CacheDirective(name string) CacheControlCheck {
+	return func(cc CacheControl) error {
+		if _, ok := cc[strings.ToLower(name)]; !ok {
+			return fmt.Errorf("Cache-Control %q lacks directive %s", cc, name)
+		}
+		return nil
+	}
+}
+
+// CacheNoDirective checks that the directive is absent, e.g. that a response is not marked public.
+func CacheNoDirective(name string) CacheControlCheck {
+	return func(cc CacheControl) error {
+		if _, ok := cc[strings.ToLower(name)]; ok {
+			return fmt.Errorf("Cache-Control %q has unexpected directive %s", cc, name)
+		}
+		return nil
+	}
+}
+
+// CacheDirectiveValue checks the argument of a directive, like s-maxage=60.
+func CacheDirectiveValue(name, value string) CacheControlCheck {
+	return func(cc CacheControl) error {
+		arg, ok := cc[strings.ToLower(name)]
+		if !ok {
+			return fmt.Errorf("Cache-Control %q lacks directive %s", cc, name)
+		}
+		if arg != value {
+			return fmt.Errorf("Cache-Control directive %s is %q, expected %q", name, arg, value)
+		}
+		return nil
+	}
+}
+
+// CacheMaxAge checks that the max-age directive lies between min and max.
+func CacheMaxAge(min, max time.Duration) CacheControlCheck {
+	return func(cc CacheControl) error {
+		arg, ok := cc["max-age"]
+		if !ok {
+			return fmt.Errorf("Cache-Control %q lacks directive max-age", cc)
+		}
+		seconds, err := strconv.Atoi(arg)
+		if err != nil || seconds < 0 {
+			return fmt.Errorf("Cache-Control has invalid max-age %q", arg)
+		}
+		if age := time.Duration(seconds) * time.Second; age < min || age > max {
+			return fmt.Errorf("Cache-Control max-age is %s, expected between %s and %s", age, min, max)
+		}
+		return nil
+	}
+}
+
+// TestCacheControl asserts that the response has a Cache-Control header passing all checks.
+func TestCacheControl(checks ...CacheControlCheck) func(*testing.T, *RequestHandler) {
+	return func(t *testing.T, rh *RequestHandler) {
+		values := rh.Response.Header.Values("Cache-Control")
+		if len(values) == 0 {
+			assert.Fail(t, "response has no Cache-Control header")
+			return
+		}
+		cc := ParseCacheControl(values...)
+		for _, check := range checks {
+			if err := check(cc); err != nil {
+				assert.Fail(t, err.Error())
+			}
+		}
+	}
+}
+
+// TestVary asserts that the Vary header of the response lists all of the given request headers, or is "*".
+func TestVary(headers ...string) func(*testing.T, *RequestHandler) {
+	return func(t *testing.T, rh *RequestHandler) {
+		vary := headerNames(rh.Response.Header, "Vary")
+		if vary["*"] {
+			return
+		}
+		for _, name := range headers {
+			assert.True(t, vary[http.CanonicalHeaderKey(name)], "Vary %q does not list %s", rh.Response.Header.Values("Vary"), name)
+		}
+	}
+}
+
+// TestAge asserts that the Age header, set by caches like CDNs, lies between min and max. A missing Age counts as 0.
+// Use a min above 0 to assert that the response was served from a cache.
+func TestAge(min, max time.Duration) func(*testing.T, *RequestHandler) {
+	return func(t *testing.T, rh *RequestHandler) {
+		age := time.Duration(0)
+		if v := rh.Response.Header.Get("Age"); v != "" {
+			seconds, err := strconv.Atoi(v)
+			if err != nil || seconds < 0 {
+				assert.Fail(t, fmt.Sprintf("response has invalid Age %q", v))
+				return
+			}
+			age = time.Duration(seconds) * time.Second
+		}
+		assert.True(t, age >= min && age <= max, "Age is %s, expected between %s and %s", age, min, max)
+	}
+}
+
+// headerNames returns the header names listed in a comma separated header like Vary, in canonical form.
+func headerNames(h http.Header, name string) map[string]bool {
+	names := map[string]bool{}
+	for _, v := range h.Values(name) {
+		for _, n := range strings.Split(v, ",") {
+			if n = strings.TrimSpace(n); n != "" {
+				names[http.CanonicalHeaderKey(n)] = true
+			}
+		}
+	}
+	return names
+}
@@ -0,0 +1,158 @@
+package goe2e_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	goe2e "github.com/J-Bockhofer/goe2e/pkg"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newCacheServer() *httptest.Server {
+	modified := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	var version atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/doc", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Cache-Control", "public, max-age=300, s-maxage=600")
+		w.Header().Set("Vary", "Accept-Encoding, Authorization")
+		w.Header().Set("Age", "42")
+		http.ServeContent(w, r, "doc.json", modified, strings.NewReader(`{"id":1}`))
+	})
+	mux.HandleFunc("/dated", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-cache")
+		http.ServeContent(w, r, "dated.json", modified, strings.NewReader(`{"id":2}`))
+	})
+	mux.HandleFunc("/changing", func(w http.ResponseWriter, r *http.Request) {
+		etag := fmt.Sprintf(`"v%d"`, version.Add(1))
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", "private, no-store")
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		fmt.Fprint(w, `{"id":3}`)
+	})
+	mux.HandleFunc("/plain", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{}`)
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestCaching(t *testing.T) {
+	srv := newCacheServer()
+	defer srv.Close()
+
+	goe2e.TestRequest(t, &goe2e.TestConfig{
+		Name:     "GET /doc",
+		SpecOpts: []goe2e.SpecOption{goe2e.WithUrl(srv.URL + "/doc")},
+		PostTestStatements: []goe2e.TestStatement{
+			{Description: "not modified", Statement: goe2e.TestNotModified()},
+			{Description: "cache control", Statement: goe2e.TestCacheControl(
+				goe2e.CacheDirective("public"),
+				goe2e.CacheNoDirective("no-store"),
+				goe2e.CacheDirectiveValue("s-maxage", "600"),
+				goe2e.CacheMaxAge(time.Minute, 10*time.Minute),
+			)},
+			{Description: "vary", Statement: goe2e.TestVary("accept-encoding", "Authorization")},
+			{Description: "age", Statement: goe2e.TestAge(time.Second, time.Minute)},
+		},
+	})
+
+	goe2e.TestRequest(t, &goe2e.TestConfig{
+		Name:     "GET /dated",
+		SpecOpts: []goe2e.SpecOption{goe2e.WithUrl(srv.URL + "/dated")},
+		PostTestStatements: []goe2e.TestStatement{
+			{Description: "not modified", Statement: goe2e.TestNotModified()},
+			{Description: "cache control", Statement: goe2e.TestCacheControl(goe2e.CacheDirective("no-cache"))},
+			{Description: "age", Statement: goe2e.TestAge(0, 0)},
+		},
+	})
+
+	goe2e.TestRequest(t, &goe2e.TestConfig{
+		Name:     "GET /changing",
+		SpecOpts: []goe2e.SpecOption{goe2e.WithUrl(srv.URL + "/changing")},
+		PostTestStatements: []goe2e.TestStatement{
+			{Description: "modified", Statement: goe2e.TestModified()},
+			{Description: "cache control", Statement: goe2e.TestCacheControl(goe2e.CacheDirective("private"), goe2e.CacheDirective("No-Store"))},
+		},
+	})
+
+	t.Run("Revalidate", func(t *testing.T) {
+		rh, err := goe2e.RunConfig(&goe2e.TestConfig{
+			Name:     "GET /doc",
+			SpecOpts: []goe2e.SpecOption{goe2e.WithUrl(srv.URL + "/doc")},
+		})
+		if !assert.NoError(t, err) {
+			return
+		}
+		conditional, err := rh.Revalidate()
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, `"v1"`, conditional.GetRequest().Header.Get("If-None-Match"))
+		assert.Equal(t, "Wed, 01 May 2024 12:00:00 GMT", conditional.GetRequest().Header.Get("If-Modified-Since"))
+		assert.Equal(t, http.StatusNotModified, conditional.Response.StatusCode)
+		assert.Empty(t, rh.GetRequest().Header.Get("If-None-Match"), "original request is not modified")
+	})
+
+	t.Run("Revalidate without validators", func(t *testing.T) {
+		rh, err := goe2e.RunConfig(&goe2e.TestConfig{
+			Name:     "GET /plain",
+			SpecOpts: []goe2e.SpecOption{goe2e.WithUrl(srv.URL + "/plain")},
+		})
+		if assert.NoError(t, err) {
+			_, err = rh.Revalidate()
+			assert.EqualError(t, err, "response has neither ETag nor Last-Modified to revalidate with")
+		}
+	})
+
+	goe2e.TestRequest(t, &goe2e.TestConfig{
+		Name:        "GET /dated If-Modified-Since",
+		SpecOpts:    []goe2e.SpecOption{goe2e.WithUrl(srv.URL + "/dated")},
+		RequestMods: []goe2e.RequestModifier{goe2e.WithIfModifiedSince(time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC))},
+		PostTestStatements: []goe2e.TestStatement{
+			{Description: "status", Statement: goe2e.TestStatusCode(http.StatusNotModified)},
+		},
+	})
+
+	goe2e.TestRequest(t, &goe2e.TestConfig{
+		Name:        "GET /doc If-None-Match stale",
+		SpecOpts:    []goe2e.SpecOption{goe2e.WithUrl(srv.URL + "/doc")},
+		RequestMods: []goe2e.RequestModifier{goe2e.WithIfNoneMatch(`"v0"`)},
+		PostTestStatements: []goe2e.TestStatement{
+			{Description: "status", Statement: goe2e.TestStatusCode(http.StatusOK)},
+		},
+	})
+}
+
+func TestParseCacheControl(t *testing.T) {
+	testCases := []struct {
+		name     string
+		values   []string
+		expected goe2e.CacheControl
+	}{
+		{"Empty", nil, goe2e.CacheControl{}},
+		{"Directives", []string{"public, max-age=60"}, goe2e.CacheControl{"public": "", "max-age": "60"}},
+		{"Quoted and mixed case", []string{`Private="Set-Cookie", NO-CACHE`}, goe2e.CacheControl{"private": "Set-Cookie", "no-cache": ""}},
+		{"Multiple headers", []string{"no-store", "must-revalidate,"}, goe2e.CacheControl{"no-store": "", "must-revalidate": ""}},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, goe2e.ParseCacheControl(tt.values...))
+		})
+	}
+
+	cc := goe2e.ParseCacheControl("public, max-age=60")
+	assert.Equal(t, "max-age=60, public", cc.String())
+	assert.NoError(t, goe2e.CacheMaxAge(time.Minute, time.Minute)(cc))
+	assert.EqualError(t, goe2e.CacheMaxAge(0, time.Second)(cc), "Cache-Control max-age is 1m0s, expected between 0s and 1s")
+	assert.Error(t, goe2e.CacheDirective("private")(cc))
+	assert.Error(t, goe2e.CacheNoDirective("public")(cc))
+}
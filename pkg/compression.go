@@ -0,0 +1,187 @@
+package goe2e
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	EncodingGzip    string = "gzip"
+	EncodingDeflate string = "deflate"
+	EncodingBrotli  string = "br"
+)
+
+// ContentEncoding compresses and decompresses bodies for a value of the Content-Encoding header.
+type ContentEncoding struct {
+	Compress   func(w io.Writer) (io.WriteCloser, error)
+	Decompress func(r io.Reader) (io.ReadCloser, error)
+}
+
+var (
+	contentEncodingsMu sync.RWMutex
+	contentEncodings   = map[string]ContentEncoding{
+		EncodingGzip: {
+			Compress: func(w io.Writer) (io.WriteCloser, error) { return gzip.NewWriter(w), nil },
+			Decompress: func(r io.Reader) (io.ReadCloser, error) {
+				return gzip.NewReader(r)
+			},
+		},
+		// deflate in HTTP is the zlib format, not raw deflate
+		EncodingDeflate: {
+			Compress: func(w io.Writer) (io.WriteCloser, error) { return zlib.NewWriter(w), nil },
+			Decompress: func(r io.Reader) (io.ReadCloser, error) {
+				return zlib.NewReader(r)
+			},
+		},
+		EncodingBrotli: {
+			Compress: func(w io.Writer) (io.WriteCloser, error) { return brotli.NewWriter(w), nil },
+			Decompress: func(r io.Reader) (io.ReadCloser, error) {
+				return io.NopCloser(brotli.NewReader(r)), nil
+			},
+		},
+	}
+)
+
+// RegisterContentEncoding adds support for another Content-Encoding, like zstd, or replaces a built-in one.
+func RegisterContentEncoding(name string, enc ContentEncoding) {
+	contentEncodingsMu.Lock()
+	defer contentEncodingsMu.Unlock()
+	contentEncodings[strings.ToLower(name)] = enc
+}
+
+func lookupContentEncoding(name string) (ContentEncoding, error) {
+	contentEncodingsMu.RLock()
+	defer contentEncodingsMu.RUnlock()
+	enc, ok := contentEncodings[strings.ToLower(name)]
+	if !ok {
+		return ContentEncoding{}, fmt.Errorf("unsupported Content-Encoding %q, add it with RegisterContentEncoding", name)
+	}
+	return enc, nil
+}
+
+// WithCompression compresses the request body with the given encoding, e.g. EncodingGzip, and sets the Content-Encoding header.
+// It has to be passed after the option setting the body.
+func WithCompression(encoding string) SpecOption {
+	return func(rs *Spec) error {
+		enc, err := lookupContentEncoding(encoding)
+		if err != nil {
+			return fmt.Errorf("spec option WithCompression failed: %w", err)
+		}
+		var buf bytes.Buffer
+		w, err := enc.Compress(&buf)
+		if err != nil {
+			return fmt.Errorf("spec option WithCompression failed - %s: %w", encoding, err)
+		}
+		if _, err := w.Write(rs.Body); err != nil {
+			return fmt.Errorf("spec option WithCompression failed - %s: %w", encoding, err)
+		}
+		if err := w.Close(); err != nil {
+			return fmt.Errorf("spec option WithCompression failed - %s: %w", encoding, err)
+		}
+		rs.Body = buf.Bytes()
+		rs.Header.Set("Content-Encoding", encoding)
+		return nil
+	}
+}
+
+// WithAcceptEncoding sets the Accept-Encoding header.
+// Setting it keeps the http.Transport from transparently decompressing gzip responses and removing their Content-Encoding header,
+// the handler decodes the body instead, so the encoding the server chose can be asserted with TestContentEncoding.
+func WithAcceptEncoding(encodings ...string) RequestModifier {
+	return func(r *http.Request) error {
+		r.Header.Set("Accept-Encoding", strings.Join(encodings, ", "))
+		return nil
+	}
+}
+
+// responseEncodings returns the encodings of a response in the order they were applied, without identity.
+func responseEncodings(resp *http.Response) []string {
+	var encodings []string
+	for _, v := range resp.Header.Values("Content-Encoding") {
+		for _, enc := range strings.Split(v, ",") {
+			enc = strings.TrimSpace(enc)
+			if enc != "" && !strings.EqualFold(enc, "identity") {
+				encodings = append(encodings, enc)
+			}
+		}
+	}
+	return encodings
+}
+
+// unsupportedEncoding returns an error naming the first encoding of the response that is not registered, or nil if all are.
+func unsupportedEncoding(resp *http.Response) error {
+	if resp == nil || resp.Uncompressed {
+		return nil
+	}
+	for _, encoding := range responseEncodings(resp) {
+		if _, err := lookupContentEncoding(encoding); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeBody wraps body with a decompressing reader for each encoding of the response, undoing the last applied first.
+// Returns body unchanged if the response is not encoded, was already decompressed by the transport
+// or uses an encoding that is not registered, which is only reported by assertions like TestContentEncoding.
+func decodeBody(resp *http.Response, body io.ReadCloser) (io.ReadCloser, error) {
+	if resp.Uncompressed || unsupportedEncoding(resp) != nil {
+		return body, nil
+	}
+	encodings := responseEncodings(resp)
+	decoded := &decodedBody{Reader: body, closers: []io.Closer{body}}
+	for i := len(encodings) - 1; i >= 0; i-- {
+		enc, _ := lookupContentEncoding(encodings[i])
+		rc, err := enc.Decompress(decoded.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("decoding %s response body failed: %w", encodings[i], err)
+		}
+		decoded.Reader = rc
+		decoded.closers = append(decoded.closers, rc)
+	}
+	if len(decoded.closers) == 1 {
+		return body, nil
+	}
+	return decoded, nil
+}
+
+// decodedBody reads the decompressed body and closes all readers, including the original body.
+type decodedBody struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (db *decodedBody) Close() error {
+	var err error
+	for i := len(db.closers) - 1; i >= 0; i-- {
+		if cerr := db.closers[i].Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// TestContentEncoding asserts the Content-Encoding the server chose for the response, e.g. EncodingGzip.
+// Use it together with WithAcceptEncoding, otherwise the http.Transport may remove the header when decompressing.
+func TestContentEncoding(encoding string) func(*testing.T, *RequestHandler) {
+	return func(t *testing.T, rh *RequestHandler) {
+		if rh.Response.Uncompressed {
+			assert.Fail(t, "response was decompressed by the http.Transport, which removed the Content-Encoding header", "set the Accept-Encoding header with WithAcceptEncoding")
+			return
+		}
+		assert.Equal(t, encoding, rh.Response.Header.Get("Content-Encoding"))
+		if err := unsupportedEncoding(rh.Response); err != nil {
+			assert.Fail(t, "response body was not decoded", err.Error())
+		}
+	}
+}
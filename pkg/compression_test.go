@@ -0,0 +1,212 @@
+package goe2e_test
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	goe2e "github.com/J-Bockhofer/goe2e/pkg"
+
+	"github.com/andybalholm/brotli"
+	"github.com/stretchr/testify/assert"
+)
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+func newCompressionServer() *httptest.Server {
+	payload := strings.Repeat(`{"name":"john","age":32}`+"\n", 50)
+	compress := func(w http.ResponseWriter, r *http.Request) io.WriteCloser {
+		accept := r.Header.Get("Accept-Encoding")
+		switch {
+		case strings.Contains(accept, "br"):
+			w.Header().Set("Content-Encoding", "br")
+			return brotli.NewWriter(w)
+		case strings.Contains(accept, "gzip"):
+			w.Header().Set("Content-Encoding", "gzip")
+			return gzip.NewWriter(w)
+		case strings.Contains(accept, "deflate"):
+			w.Header().Set("Content-Encoding", "deflate")
+			return zlib.NewWriter(w)
+		}
+		return nopWriteCloser{w}
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/echo", func(w http.ResponseWriter, r *http.Request) {
+		var body io.Reader = r.Body
+		var err error
+		switch r.Header.Get("Content-Encoding") {
+		case "gzip":
+			body, err = gzip.NewReader(r.Body)
+		case "deflate":
+			body, err = zlib.NewReader(r.Body)
+		case "br":
+			body = brotli.NewReader(r.Body)
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		b, err := io.ReadAll(body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(goe2e.H{"encoding": r.Header.Get("Content-Encoding"), "body": string(b)})
+	})
+	mux.HandleFunc("/data", func(w http.ResponseWriter, r *http.Request) {
+		cw := compress(w, r)
+		defer cw.Close()
+		fmt.Fprint(cw, payload)
+	})
+	// /vendor uses an encoding the handler does not know
+	mux.HandleFunc("/vendor", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "zstd")
+		w.Write([]byte("opaque"))
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestWithCompression(t *testing.T) {
+	srv := newCompressionServer()
+	defer srv.Close()
+
+	for _, encoding := range []string{goe2e.EncodingGzip, goe2e.EncodingDeflate, goe2e.EncodingBrotli} {
+		goe2e.TestRequest(t, &goe2e.TestConfig{
+			Name: "POST /echo " + encoding,
+			SpecOpts: []goe2e.SpecOption{
+				goe2e.WithUrl(srv.URL + "/echo"),
+				goe2e.WithMethod(http.MethodPost),
+				goe2e.WithJSON(goe2e.H{"name": "john"}),
+				goe2e.WithCompression(encoding),
+			},
+			PreTestStatements: []goe2e.TestStatement{
+				{Description: "header", Statement: func(t *testing.T, rh *goe2e.RequestHandler) {
+					assert.Equal(t, encoding, rh.GetRequest().Header.Get("Content-Encoding"))
+				}},
+			},
+			PostTestStatements: []goe2e.TestStatement{
+				{Description: "decoded by server", Statement: goe2e.TestDecoded(func(t *testing.T, body goe2e.H) {
+					assert.Equal(t, goe2e.H{"encoding": encoding, "body": `{"name":"john"}`}, body)
+				})},
+			},
+		})
+	}
+
+	t.Run("Unsupported", func(t *testing.T) {
+		_, err := goe2e.NewSpec(goe2e.WithBody([]byte("x")), goe2e.WithCompression("zstd"))
+		assert.ErrorContains(t, err, `unsupported Content-Encoding "zstd"`)
+	})
+}
+
+func TestResponseCompression(t *testing.T) {
+	srv := newCompressionServer()
+	defer srv.Close()
+
+	for _, encoding := range []string{goe2e.EncodingGzip, goe2e.EncodingDeflate, goe2e.EncodingBrotli} {
+		goe2e.TestRequest(t, &goe2e.TestConfig{
+			Name:        "GET /data " + encoding,
+			SpecOpts:    []goe2e.SpecOption{goe2e.WithUrl(srv.URL + "/data")},
+			RequestMods: []goe2e.RequestModifier{goe2e.WithAcceptEncoding(encoding)},
+			PostTestStatements: []goe2e.TestStatement{
+				{Description: "encoding", Statement: goe2e.TestContentEncoding(encoding)},
+				{Description: "bodies", Statement: func(t *testing.T, rh *goe2e.RequestHandler) {
+					assert.True(t, strings.HasPrefix(string(rh.ResponseBody), `{"name":"john","age":32}`))
+					assert.Less(t, len(rh.RawResponseBody), len(rh.ResponseBody))
+				}},
+			},
+		})
+	}
+
+	goe2e.TestRequest(t, &goe2e.TestConfig{
+		Name:     "GET /data transparent",
+		SpecOpts: []goe2e.SpecOption{goe2e.WithUrl(srv.URL + "/data")},
+		PostTestStatements: []goe2e.TestStatement{
+			{Description: "decompressed by transport", Statement: func(t *testing.T, rh *goe2e.RequestHandler) {
+				assert.True(t, rh.Response.Uncompressed)
+				assert.Empty(t, rh.Response.Header.Get("Content-Encoding"))
+				assert.Equal(t, rh.ResponseBody, rh.RawResponseBody)
+			}},
+		},
+	})
+
+	goe2e.TestRequest(t, &goe2e.TestConfig{
+		Name:        "GET /data identity",
+		SpecOpts:    []goe2e.SpecOption{goe2e.WithUrl(srv.URL + "/data")},
+		RequestMods: []goe2e.RequestModifier{goe2e.WithAcceptEncoding("identity")},
+		PostTestStatements: []goe2e.TestStatement{
+			{Description: "encoding", Statement: goe2e.TestContentEncoding("")},
+			{Description: "bodies", Statement: func(t *testing.T, rh *goe2e.RequestHandler) {
+				assert.Equal(t, rh.ResponseBody, rh.RawResponseBody)
+			}},
+		},
+	})
+
+	goe2e.TestRequest(t, &goe2e.TestConfig{
+		Name:        "GET /data streamed",
+		SpecOpts:    []goe2e.SpecOption{goe2e.WithUrl(srv.URL + "/data")},
+		RequestMods: []goe2e.RequestModifier{goe2e.WithAcceptEncoding(goe2e.EncodingGzip)},
+		Stream:      &goe2e.StreamConfig{},
+		RecordStatements: []goe2e.RecordStatement{
+			{Description: "person", Statement: goe2e.TestRecordJSON(func(t *testing.T, p person) {
+				assert.Equal(t, person{"john", 32}, p)
+			})},
+		},
+		PostTestStatements: []goe2e.TestStatement{
+			{Description: "count", Statement: goe2e.TestRecordCount(50)},
+		},
+	})
+
+	t.Run("Registered encoding", func(t *testing.T) {
+		goe2e.RegisterContentEncoding("x-test", goe2e.ContentEncoding{
+			Compress: func(w io.Writer) (io.WriteCloser, error) { return nopWriteCloser{w}, nil },
+			Decompress: func(r io.Reader) (io.ReadCloser, error) {
+				return io.NopCloser(r), nil
+			},
+		})
+		rs, err := goe2e.NewSpec(goe2e.WithBody([]byte("plain")), goe2e.WithCompression("x-test"))
+		if assert.NoError(t, err) {
+			assert.Equal(t, []byte("plain"), rs.Body)
+			assert.Equal(t, "x-test", rs.Header.Get("Content-Encoding"))
+		}
+	})
+
+	t.Run("Unsupported encoding", func(t *testing.T) {
+		rh, err := goe2e.NewRequestHandler(goe2e.WithSpecOpts(goe2e.WithUrl(srv.URL + "/vendor")))
+		if !assert.NoError(t, err) || !assert.NoError(t, rh.RunRequest()) {
+			return
+		}
+		assert.Equal(t, []byte("opaque"), rh.ResponseBody)
+		assert.Equal(t, rh.RawResponseBody, rh.ResponseBody)
+
+		mock := &testing.T{}
+		goe2e.TestContentEncoding("zstd")(mock, rh)
+		assert.True(t, mock.Failed(), "the undecoded body is reported")
+		mock = &testing.T{}
+		goe2e.TestDecoded(func(*testing.T, goe2e.H) {})(mock, rh)
+		assert.True(t, mock.Failed())
+	})
+
+	t.Run("Max response size applies to the decoded body", func(t *testing.T) {
+		rh, err := goe2e.NewRequestHandler(
+			goe2e.WithSpecOpts(goe2e.WithUrl(srv.URL+"/data")),
+			goe2e.WithMaxResponseSize(500),
+		)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.NoError(t, rh.ModifyRequest(goe2e.WithAcceptEncoding(goe2e.EncodingGzip)))
+		err = rh.RunRequest()
+		assert.ErrorIs(t, err, goe2e.ErrBodyTooLarge)
+		assert.ErrorContains(t, err, "decoding response body failed")
+	})
+}
@@ -0,0 +1,268 @@
+package goe2e
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// CORSConfig describes a cross-origin request and the CORS policy the server is expected to apply to it, see TestCORS.
+type CORSConfig struct {
+	// TestConfig describes the actual request, only its Name, Tags, Profile, SpecOpts, HandlerOpts, RequestMods and PreFunc are used.
+	TestConfig
+	// Origin the request is sent from, like https://app.example.com.
+	Origin string
+	// RequestHeaders are announced in the Access-Control-Request-Headers of the preflight.
+	// Defaults to the headers of the actual request that a browser does not consider safe, like Authorization.
+	RequestHeaders []string
+	// Credentials expects cookies and authentication to be allowed, which rules out the wildcard "*" in all Access-Control headers.
+	Credentials bool
+	// MinMaxAge is the minimum time the preflight response may be cached for, 0 skips the check.
+	MinMaxAge time.Duration
+	// ExposeHeaders are the response headers the frontend needs to read, they have to be listed in Access-Control-Expose-Headers.
+	ExposeHeaders []string
+}
+
+// corsSafelistedHeaders are the request headers a browser sends without asking in a preflight, as long as their value is safe.
+var corsSafelistedHeaders = map[string]bool{
+	"Accept":           true,
+	"Accept-Language":  true,
+	"Content-Language": true,
+	"Content-Type":     true,
+}
+
+// corsSafelistedContentTypes are the Content-Type values that do not require a preflight.
+var corsSafelistedContentTypes = map[string]bool{
+	ContentHeaderForm:     true,
+	"multipart/form-data": true,
+	"text/plain":          true,
+}
+
+// CORSRequestHeaders returns the lowercased and sorted names of the headers a browser would announce in a preflight for h.
+func CORSRequestHeaders(h http.Header) []string {
+	var names []string
+	for name := range h {
+		name = http.CanonicalHeaderKey(name)
+		if name == "Origin" {
+			continue
+		}
+		if corsSafelistedHeaders[name] {
+			if name != "Content-Type" {
+				continue
+			}
+			mediaType, _, _ := strings.Cut(h.Get(name), ";")
+			if corsSafelistedContentTypes[strings.ToLower(strings.TrimSpace(mediaType))] {
+				continue
+			}
+		}
+		names = append(names, strings.ToLower(name))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Preflight sends the OPTIONS request a browser would send from origin before the handler's request, announcing its method and the headers.
+// Like in a browser, the preflight carries no credentials: the cookie jar of the client and the round trippers of the handler,
+// e.g. from WithLogin, are not used, while its transport options, like TLS settings, still apply.
+// The returned handler holds the preflight response.
+func (rh *RequestHandler) Preflight(origin string, headers ...string) (*RequestHandler, error) {
+	if rh.spec == nil {
+		return nil, fmt.Errorf("no request specifications initialized before executing")
+	}
+	r, err := http.NewRequestWithContext(rh.spec.Request.Context(), http.MethodOptions, rh.spec.Request.URL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	r.Header.Set("Origin", origin)
+	r.Header.Set("Access-Control-Request-Method", rh.spec.Request.Method)
+	if len(headers) > 0 {
+		r.Header.Set("Access-Control-Request-Headers", strings.Join(headers, ","))
+	}
+	preflight := rh.derive(r)
+	preflight.spec.Body = nil
+	preflight.wrappers = nil
+	if rh.Client != nil {
+		client := *rh.Client
+		client.Jar = nil
+		preflight.Client = &client
+	}
+	if err := preflight.RunRequest(); err != nil {
+		return nil, fmt.Errorf("preflight failed: %w", err)
+	}
+	return preflight, nil
+}
+
+// TestCORS sends the preflight for a cross-origin request and checks that it allows the origin, method and headers.
+// It then sends the actual request with the Origin header and checks the CORS headers of the response.
+func TestCORS(t *testing.T, cc *CORSConfig) {
+	rh, ok := cc.setup(t, "cors")
+	if !ok {
+		return
+	}
+	defer rh.Close()
+	headers := cc.RequestHeaders
+	if headers == nil {
+		headers = CORSRequestHeaders(rh.GetRequest().Header)
+	}
+	method := rh.GetRequest().Method
+
+	preflight, err := rh.Preflight(cc.Origin, headers...)
+	if err != nil {
+		failf(t, "cors: %s \n%s", cc.Name, err.Error())
+		return
+	}
+	preflightChecks := []struct {
+		description string
+		check       func() error
+	}{
+		{"status", func() error { return checkPreflightStatus(preflight.Response) }},
+		{"origin", func() error { return checkAllowOrigin(preflight.Response.Header, cc.Origin, cc.Credentials) }},
+		{"method", func() error { return checkAllowMethod(preflight.Response.Header, method, cc.Credentials) }},
+		{"headers", func() error { return checkAllowHeaders(preflight.Response.Header, headers, cc.Credentials) }},
+		{"credentials", func() error { return checkAllowCredentials(preflight.Response.Header, cc.Credentials) }},
+		{"max-age", func() error { return checkMaxAge(preflight.Response.Header, cc.MinMaxAge) }},
+	}
+	for _, tt := range preflightChecks {
+		label := fmt.Sprintf("%s/[PREFLIGHT]/%s", cc.Name, tt.description)
+		t.Run(label, func(t *testing.T) {
+			if err := tt.check(); err != nil {
+				assert.Fail(t, err.Error())
+			}
+		})
+	}
+
+	rh.GetRequest().Header.Set("Origin", cc.Origin)
+	if err := rh.RunRequest(); err != nil {
+		failf(t, "cors: %s \nRequest execution failed: %s", cc.Name, err.Error())
+		return
+	}
+	responseChecks := []struct {
+		description string
+		check       func() error
+	}{
+		{"origin", func() error { return checkAllowOrigin(rh.Response.Header, cc.Origin, cc.Credentials) }},
+		{"credentials", func() error { return checkAllowCredentials(rh.Response.Header, cc.Credentials) }},
+		{"expose-headers", func() error { return checkExposeHeaders(rh.Response.Header, cc.ExposeHeaders, cc.Credentials) }},
+		{"vary", func() error { return checkVaryOrigin(rh.Response.Header) }},
+	}
+	for _, tt := range responseChecks {
+		label := fmt.Sprintf("%s/[CORS]/%s", cc.Name, tt.description)
+		t.Run(label, func(t *testing.T) {
+			if err := tt.check(); err != nil {
+				assert.Fail(t, err.Error())
+			}
+		})
+	}
+}
+
+func checkPreflightStatus(resp *http.Response) error {
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("preflight answered with status %d, browsers require an ok status", resp.StatusCode)
+	}
+	return nil
+}
+
+// checkAllowOrigin checks that the origin is allowed, by name or by the wildcard if no credentials are sent.
+func checkAllowOrigin(h http.Header, origin string, credentials bool) error {
+	allowed := h.Get("Access-Control-Allow-Origin")
+	switch {
+	case allowed == origin:
+		return nil
+	case allowed == "*" && credentials:
+		return fmt.Errorf("Access-Control-Allow-Origin is \"*\", which browsers reject for requests with credentials")
+	case allowed == "*":
+		return nil
+	case allowed == "":
+		return fmt.Errorf("response has no Access-Control-Allow-Origin, expected %q", origin)
+	}
+	return fmt.Errorf("Access-Control-Allow-Origin is %q, expected %q", allowed, origin)
+}
+
+// checkAllowMethod checks that the method is allowed, safelisted methods always are.
+func checkAllowMethod(h http.Header, method string, credentials bool) error {
+	if method == http.MethodGet || method == http.MethodHead || method == http.MethodPost {
+		return nil
+	}
+	allowed := map[string]bool{}
+	for _, v := range h.Values("Access-Control-Allow-Methods") {
+		for _, m := range strings.Split(v, ",") {
+			allowed[strings.TrimSpace(m)] = true
+		}
+	}
+	if allowed[method] || (allowed["*"] && !credentials) {
+		return nil
+	}
+	return fmt.Errorf("Access-Control-Allow-Methods %q does not allow %s", h.Values("Access-Control-Allow-Methods"), method)
+}
+
+// checkAllowHeaders checks that all announced headers are allowed. The wildcard never covers Authorization.
+func checkAllowHeaders(h http.Header, headers []string, credentials bool) error {
+	allowed := headerNames(h, "Access-Control-Allow-Headers")
+	var missing []string
+	for _, name := range headers {
+		name = http.CanonicalHeaderKey(name)
+		if allowed[name] || (allowed["*"] && !credentials && name != "Authorization") {
+			continue
+		}
+		missing = append(missing, name)
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("Access-Control-Allow-Headers %q does not allow %s", h.Values("Access-Control-Allow-Headers"), strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+func checkAllowCredentials(h http.Header, credentials bool) error {
+	if credentials && h.Get("Access-Control-Allow-Credentials") != "true" {
+		return fmt.Errorf("Access-Control-Allow-Credentials is %q, expected \"true\"", h.Get("Access-Control-Allow-Credentials"))
+	}
+	return nil
+}
+
+func checkMaxAge(h http.Header, min time.Duration) error {
+	if min <= 0 {
+		return nil
+	}
+	v := h.Get("Access-Control-Max-Age")
+	seconds, err := strconv.Atoi(v)
+	if err != nil {
+		return fmt.Errorf("Access-Control-Max-Age is %q, expected at least %s", v, min)
+	}
+	if age := time.Duration(seconds) * time.Second; age < min {
+		return fmt.Errorf("Access-Control-Max-Age is %s, expected at least %s", age, min)
+	}
+	return nil
+}
+
+// checkExposeHeaders checks that the frontend can read the headers of the response.
+func checkExposeHeaders(h http.Header, headers []string, credentials bool) error {
+	exposed := headerNames(h, "Access-Control-Expose-Headers")
+	var missing []string
+	for _, name := range headers {
+		if !exposed[http.CanonicalHeaderKey(name)] && !(exposed["*"] && !credentials) {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("Access-Control-Expose-Headers %q does not expose %s", h.Values("Access-Control-Expose-Headers"), strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// checkVaryOrigin checks that a response allowing a specific origin varies by Origin, so caches do not serve it to other origins.
+func checkVaryOrigin(h http.Header) error {
+	allowed := h.Get("Access-Control-Allow-Origin")
+	if allowed == "" || allowed == "*" {
+		return nil
+	}
+	if vary := headerNames(h, "Vary"); !vary["Origin"] && !vary["*"] {
+		return fmt.Errorf("response allows origin %q but does not list Origin in Vary %q", allowed, h.Values("Vary"))
+	}
+	return nil
+}
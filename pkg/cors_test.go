@@ -0,0 +1,155 @@
+package goe2e_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	goe2e "github.com/J-Bockhofer/goe2e/pkg"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newCORSServer() *httptest.Server {
+	mux := http.NewServeMux()
+	// /api allows a single origin with credentials
+	mux.HandleFunc("/api", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Origin")
+		if r.Header.Get("Origin") == "https://app.example.com" {
+			w.Header().Set("Access-Control-Allow-Origin", r.Header.Get("Origin"))
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+		}
+		if r.Method == http.MethodOptions {
+			w.Header().Set("Access-Control-Allow-Methods", "GET, PUT, DELETE")
+			w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, X-Request-Id")
+			w.Header().Set("Access-Control-Max-Age", "600")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set("Access-Control-Expose-Headers", "X-Request-Id, ETag")
+		w.Header().Set("X-Request-Id", "42")
+		w.Write([]byte(`{}`))
+	})
+	// /public allows every origin without credentials
+	mux.HandleFunc("/public", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		if r.Method == http.MethodOptions {
+			w.Header().Set("Access-Control-Allow-Methods", "*")
+			w.Header().Set("Access-Control-Allow-Headers", "*")
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.Header().Set("Access-Control-Expose-Headers", "*")
+		w.Write([]byte(`{}`))
+	})
+	// /whoami reports the credentials it received
+	mux.HandleFunc("/whoami", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Authorization", r.Header.Get("Authorization"))
+		w.Header().Set("X-Cookie", r.Header.Get("Cookie"))
+		w.WriteHeader(http.StatusNoContent)
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestCORS(t *testing.T) {
+	srv := newCORSServer()
+	defer srv.Close()
+
+	goe2e.TestCORS(t, &goe2e.CORSConfig{
+		TestConfig: goe2e.TestConfig{
+			Name:     "PUT /api",
+			SpecOpts: []goe2e.SpecOption{goe2e.WithUrl(srv.URL + "/api"), goe2e.WithMethod(http.MethodPut), goe2e.WithJSON(goe2e.H{"id": 1})},
+			RequestMods: []goe2e.RequestModifier{
+				goe2e.WithBearerToken("cors-token"),
+				goe2e.WithContentType(goe2e.ContentHeaderJSON),
+				goe2e.WithHeaders(goe2e.D{"X-Request-Id": "42"}),
+			},
+		},
+		Origin:        "https://app.example.com",
+		Credentials:   true,
+		MinMaxAge:     5 * time.Minute,
+		ExposeHeaders: []string{"x-request-id"},
+	})
+
+	goe2e.TestCORS(t, &goe2e.CORSConfig{
+		TestConfig: goe2e.TestConfig{
+			Name:        "PATCH /public",
+			SpecOpts:    []goe2e.SpecOption{goe2e.WithUrl(srv.URL + "/public"), goe2e.WithMethod(http.MethodPatch)},
+			RequestMods: []goe2e.RequestModifier{goe2e.WithHeaders(goe2e.D{"X-Trace": "1"})},
+		},
+		Origin:        "https://other.example.com",
+		ExposeHeaders: []string{"X-Anything"},
+	})
+
+	t.Run("Preflight", func(t *testing.T) {
+		rh, err := goe2e.NewRequestHandler(goe2e.WithSpecOpts(goe2e.WithUrl(srv.URL+"/api"), goe2e.WithMethod(http.MethodDelete)))
+		if !assert.NoError(t, err) {
+			return
+		}
+		preflight, err := rh.Preflight("https://evil.example.com", "authorization", "x-request-id")
+		if !assert.NoError(t, err) {
+			return
+		}
+		r := preflight.GetRequest()
+		assert.Equal(t, http.MethodOptions, r.Method)
+		assert.Equal(t, "https://evil.example.com", r.Header.Get("Origin"))
+		assert.Equal(t, http.MethodDelete, r.Header.Get("Access-Control-Request-Method"))
+		assert.Equal(t, "authorization,x-request-id", r.Header.Get("Access-Control-Request-Headers"))
+		assert.Equal(t, http.StatusNoContent, preflight.Response.StatusCode)
+		assert.Empty(t, preflight.Response.Header.Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, http.MethodDelete, rh.GetRequest().Method, "original request is not modified")
+	})
+
+	t.Run("Preflight without credentials", func(t *testing.T) {
+		session, err := goe2e.NewSession()
+		if !assert.NoError(t, err) {
+			return
+		}
+		u, _ := url.Parse(srv.URL)
+		session.Client.Jar.SetCookies(u, []*http.Cookie{{Name: "session", Value: "s1"}})
+		rh, err := goe2e.NewRequestHandler(
+			goe2e.WithSpecOpts(goe2e.WithUrl(srv.URL+"/whoami"), goe2e.WithMethod(http.MethodPut)),
+			goe2e.WithSession(session),
+			goe2e.WithRoundTripper(func(next http.RoundTripper) http.RoundTripper {
+				return goe2e.RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+					r = r.Clone(r.Context())
+					r.Header.Set("Authorization", "Bearer cors-token")
+					return next.RoundTrip(r)
+				})
+			}),
+		)
+		if !assert.NoError(t, err) {
+			return
+		}
+		preflight, err := rh.Preflight("https://app.example.com")
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Empty(t, preflight.Response.Header.Get("X-Authorization"))
+		assert.Empty(t, preflight.Response.Header.Get("X-Cookie"))
+
+		if assert.NoError(t, rh.RunRequest()) {
+			assert.Equal(t, "Bearer cors-token", rh.Response.Header.Get("X-Authorization"))
+			assert.Equal(t, "session=s1", rh.Response.Header.Get("X-Cookie"))
+		}
+	})
+}
+
+func TestCORSRequestHeaders(t *testing.T) {
+	testCases := []struct {
+		name     string
+		header   http.Header
+		expected []string
+	}{
+		{"Safelisted", http.Header{"Accept": {"*/*"}, "Content-Type": {"text/plain; charset=utf-8"}}, nil},
+		{"JSON content type", http.Header{"Content-Type": {goe2e.ContentHeaderJSON}}, []string{"content-type"}},
+		{"Sorted and lowercased", http.Header{"X-B": {"1"}, "Authorization": {"x"}, "X-A": {"2"}, "Origin": {"o"}}, []string{"authorization", "x-a", "x-b"}},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, goe2e.CORSRequestHeaders(tt.header))
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package goe2e
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// TokenFromHTMLMeta extracts the token from the content of a meta tag, like <meta name="csrf-token" content="...">.
+func TokenFromHTMLMeta(name string) TokenExtractor {
+	return func(rh *RequestHandler) (string, error) {
+		for _, attrs := range htmlTagAttrs(rh.ResponseBody, "meta") {
+			if attrs["name"] == name && attrs["content"] != "" {
+				return attrs["content"], nil
+			}
+		}
+		return "", fmt.Errorf("token extraction failed: no meta tag %s in response body", name)
+	}
+}
+
+// TokenFromFormInput extracts the token from the value of an input, like <input type="hidden" name="_csrf" value="...">.
+func TokenFromFormInput(name string) TokenExtractor {
+	return func(rh *RequestHandler) (string, error) {
+		for _, attrs := range htmlTagAttrs(rh.ResponseBody, "input") {
+			if attrs["name"] == name && attrs["value"] != "" {
+				return attrs["value"], nil
+			}
+		}
+		return "", fmt.Errorf("token extraction failed: no input %s in response body", name)
+	}
+}
+
+// ExtractCSRF stores the CSRF token read from the response as secret under key in env.
+// Use it as PostFunc of the request rendering the form, together with one of the TokenExtractors like TokenFromHTMLMeta or TokenFromCookie.
+func ExtractCSRF(extract TokenExtractor, env *Env, key string) RequestHandlerModFunc {
+	return func(rh *RequestHandler) error {
+		token, err := extract(rh)
+		if err != nil {
+			return fmt.Errorf("extracting csrf token failed: %w", err)
+		}
+		env.SetSecret(key, token)
+		return nil
+	}
+}
+
+// WithCSRFHeader sends the CSRF token stored under key in env as header, e.g. X-CSRF-Token, on state-changing requests.
+// Requests with the safe methods GET, HEAD, OPTIONS and TRACE are left untouched.
+func WithCSRFHeader(env *Env, key string, header string) RequestModifier {
+	return func(r *http.Request) error {
+		if isSafeMethod(r.Method) {
+			return nil
+		}
+		token, err := env.String(key)
+		if err != nil {
+			return fmt.Errorf("csrf header failed: %w", err)
+		}
+		r.Header.Set(header, token)
+		return nil
+	}
+}
+
+// WithCSRFFormField adds the CSRF token stored under key in env as form field to the body of state-changing requests.
+// Works with url-encoded and multipart bodies, a request without body gets a url-encoded one.
+// Requests with the safe methods GET, HEAD, OPTIONS and TRACE are left untouched.
+func WithCSRFFormField(env *Env, key string, field string) RequestModifier {
+	return func(r *http.Request) error {
+		if isSafeMethod(r.Method) {
+			return nil
+		}
+		token, err := env.String(key)
+		if err != nil {
+			return fmt.Errorf("csrf form field failed: %w", err)
+		}
+		body, err := requestBody(r)
+		if err != nil {
+			return fmt.Errorf("csrf form field failed: %w", err)
+		}
+		contentType := r.Header.Get("Content-Type")
+		if contentType == "" && len(body) == 0 {
+			contentType = "application/x-www-form-urlencoded"
+			r.Header.Set("Content-Type", contentType)
+		}
+		mediaType, params, _ := mime.ParseMediaType(contentType)
+		switch mediaType {
+		case "application/x-www-form-urlencoded":
+			form, err := url.ParseQuery(string(body))
+			if err != nil {
+				return fmt.Errorf("csrf form field failed: %w", err)
+			}
+			form.Set(field, token)
+			setRequestBody(r, []byte(form.Encode()))
+		case "multipart/form-data":
+			b, err := addMultipartField(body, params["boundary"], field, token)
+			if err != nil {
+				return fmt.Errorf("csrf form field failed: %w", err)
+			}
+			setRequestBody(r, b)
+		default:
+			return fmt.Errorf("csrf form field failed: unsupported content type %q", contentType)
+		}
+		return nil
+	}
+}
+
+// addMultipartField rewrites a multipart body with an additional field, keeping the boundary.
+func addMultipartField(body []byte, boundary string, name, value string) ([]byte, error) {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	if err := w.SetBoundary(boundary); err != nil {
+		return nil, err
+	}
+	if err := w.WriteField(name, value); err != nil {
+		return nil, err
+	}
+	mr := multipart.NewReader(bytes.NewReader(body), boundary)
+	for {
+		p, err := mr.NextRawPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if p.FormName() == name {
+			continue
+		}
+		pw, err := w.CreatePart(p.Header)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.Copy(pw, p); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func isSafeMethod(method string) bool {
+	switch strings.ToUpper(method) {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
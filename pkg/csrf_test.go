@@ -0,0 +1,162 @@
+package goe2e_test
+
+import (
+	"bytes"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	goe2e "github.com/J-Bockhofer/goe2e/pkg"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const csrfForm = `<!DOCTYPE html>
+<html><head>
+<META NAME='csrf-token' CONTENT='meta-token'>
+<meta name="description" content="a form">
+</head><body>
+<form method="post" action="/submit">
+<inputs>not an input</inputs>
+<input type=hidden name=_csrf value=input/token>
+<input type="text" name="name" value="&lt;john&gt;"/>
+</form></body></html>`
+
+func newCSRFServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/form", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "csrftoken", Value: "cookie-token"})
+		w.Header().Set("X-CSRF-Token", "header-token")
+		w.Write([]byte(csrfForm))
+	})
+	mux.HandleFunc("/submit", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-CSRF-Token") != "meta-token" && r.FormValue("_csrf") != "meta-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		fmt.Fprint(w, r.FormValue("name"))
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestCSRFExtractors(t *testing.T) {
+	srv := newCSRFServer()
+	defer srv.Close()
+	rh, err := goe2e.RunConfig(&goe2e.TestConfig{
+		Name:     "GET /form",
+		SpecOpts: []goe2e.SpecOption{goe2e.WithUrl(srv.URL + "/form")},
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	testCases := []struct {
+		name     string
+		extract  goe2e.TokenExtractor
+		expected string
+	}{
+		{"Meta", goe2e.TokenFromHTMLMeta("csrf-token"), "meta-token"},
+		{"Input", goe2e.TokenFromFormInput("_csrf"), "input/token"},
+		{"Escaped input", goe2e.TokenFromFormInput("name"), "<john>"},
+		{"Cookie", goe2e.TokenFromCookie("csrftoken"), "cookie-token"},
+		{"Header", goe2e.TokenFromHeader("X-CSRF-Token"), "header-token"},
+		{"Missing meta", goe2e.TokenFromHTMLMeta("missing"), ""},
+		{"Missing input", goe2e.TokenFromFormInput("missing"), ""},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := tt.extract(rh)
+			if tt.expected == "" {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, token)
+		})
+	}
+}
+
+func TestCSRFFlow(t *testing.T) {
+	srv := newCSRFServer()
+	defer srv.Close()
+	env := goe2e.NewEnv()
+
+	goe2e.TestRequest(t, &goe2e.TestConfig{
+		Name:     "GET /form",
+		SpecOpts: []goe2e.SpecOption{goe2e.WithUrl(srv.URL + "/form")},
+		PostFunc: goe2e.ExtractCSRF(goe2e.TokenFromHTMLMeta("csrf-token"), env, "csrf"),
+	})
+	assert.True(t, env.IsSecret("csrf"))
+
+	var multipartBody bytes.Buffer
+	mw := multipart.NewWriter(&multipartBody)
+	mw.WriteField("name", "multipart")
+	mw.Close()
+
+	testCases := []struct {
+		name     string
+		opts     []goe2e.SpecOption
+		mods     []goe2e.RequestModifier
+		expected string
+	}{
+		{"Header", []goe2e.SpecOption{goe2e.WithBody([]byte("name=header"))}, []goe2e.RequestModifier{
+			goe2e.WithContentType("application/x-www-form-urlencoded"),
+			goe2e.WithCSRFHeader(env, "csrf", "X-CSRF-Token"),
+		}, "header"},
+		{"Form field", []goe2e.SpecOption{goe2e.WithBody([]byte("name=form&_csrf=stale"))}, []goe2e.RequestModifier{
+			goe2e.WithContentType("application/x-www-form-urlencoded"),
+			goe2e.WithCSRFFormField(env, "csrf", "_csrf"),
+		}, "form"},
+		{"Form field without body", nil, []goe2e.RequestModifier{
+			goe2e.WithCSRFFormField(env, "csrf", "_csrf"),
+		}, ""},
+		{"Multipart form field", []goe2e.SpecOption{goe2e.WithBody(multipartBody.Bytes())}, []goe2e.RequestModifier{
+			goe2e.WithContentType(mw.FormDataContentType()),
+			goe2e.WithCSRFFormField(env, "csrf", "_csrf"),
+		}, "multipart"},
+	}
+	for _, tt := range testCases {
+		tc := &goe2e.TestConfig{
+			Name:        "POST /submit " + tt.name,
+			SpecOpts:    append([]goe2e.SpecOption{goe2e.WithMethod(http.MethodPost), goe2e.WithUrl(srv.URL + "/submit")}, tt.opts...),
+			RequestMods: tt.mods,
+			PostTestStatements: []goe2e.TestStatement{
+				{Description: "status 200", Statement: goe2e.TestStatusCode(http.StatusOK)},
+				{Description: "body kept", Statement: func(t *testing.T, rh *goe2e.RequestHandler) {
+					assert.Equal(t, tt.expected, string(rh.ResponseBody))
+				}},
+			},
+		}
+		goe2e.TestRequest(t, tc)
+	}
+
+	t.Run("Safe methods are untouched", func(t *testing.T) {
+		spec, err := goe2e.NewSpec(goe2e.WithUrl(srv.URL + "/submit"))
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.NoError(t, goe2e.WithCSRFHeader(env, "csrf", "X-CSRF-Token")(spec.Request))
+		assert.NoError(t, goe2e.WithCSRFFormField(env, "csrf", "_csrf")(spec.Request))
+		assert.Empty(t, spec.Request.Header.Get("X-CSRF-Token"))
+		assert.Equal(t, int64(0), spec.Request.ContentLength)
+	})
+
+	t.Run("Curl shows the form field", func(t *testing.T) {
+		rh, err := goe2e.NewRequestHandler(goe2e.WithSpecOpts(goe2e.WithMethod(http.MethodPost), goe2e.WithUrl(srv.URL+"/submit"), goe2e.WithBody([]byte("name=form"))))
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.NoError(t, rh.ModifyRequest(goe2e.WithContentType("application/x-www-form-urlencoded"), goe2e.WithCSRFFormField(env, "csrf", "_csrf")))
+		assert.Contains(t, rh.Curl(), "--data-raw '_csrf=")
+		assert.Contains(t, rh.Curl(), "&name=form'")
+	})
+
+	t.Run("Missing token", func(t *testing.T) {
+		spec, err := goe2e.NewSpec(goe2e.WithMethod(http.MethodPost))
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Error(t, goe2e.WithCSRFHeader(goe2e.NewEnv(), "csrf", "X-CSRF-Token")(spec.Request))
+	})
+}
@@ -0,0 +1,473 @@
+package goe2e
+
+import (
+	"bytes"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// SpecFromCurl parses a curl command line into a Spec and the RequestModifiers that set its headers and credentials.
+// The modifiers still have to be applied to the Spec's http.Request, e.g. via (RequestHandler).ModifyRequest.
+func SpecFromCurl(cmd string) (*Spec, []RequestModifier, error) {
+	cr, err := parseCurl(cmd)
+	if err != nil {
+		return nil, nil, err
+	}
+	spec, err := NewSpec(cr.specOpts()...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return spec, cr.requestMods(), nil
+}
+
+// TestConfigFromCurl turns a curl command line, e.g. from a bug report, into a TestConfig.
+// Test statements can be added to the returned config before passing it to TestRequest.
+func TestConfigFromCurl(name string, cmd string) (*TestConfig, error) {
+	cr, err := parseCurl(cmd)
+	if err != nil {
+		return nil, err
+	}
+	return &TestConfig{
+		Name:        name,
+		SpecOpts:    cr.specOpts(),
+		RequestMods: cr.requestMods(),
+	}, nil
+}
+
+// CurlCommand renders a http.Request and its body as a curl command line, e.g. to reproduce a failing test by hand.
+// Sensitive headers and registered secrets are redacted using the DefaultSecrets.
+func CurlCommand(r *http.Request, body []byte) string {
+	var b strings.Builder
+	b.WriteString("curl")
+	if r.Method != http.MethodGet {
+		b.WriteString(" -X " + r.Method)
+	}
+	b.WriteString(" " + shellQuote(DefaultSecrets.Redact(r.URL.String())))
+	header := DefaultSecrets.RedactHeader(r.Header)
+	names := make([]string, 0, len(header))
+	for k := range header {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		for _, v := range header[k] {
+			b.WriteString(" -H " + shellQuote(k+": "+v))
+		}
+	}
+	if len(body) > 0 {
+		b.WriteString(" --data-raw " + shellQuote(DefaultSecrets.Redact(string(body))))
+	}
+	return b.String()
+}
+
+// Curl renders the handler's request as a curl command line, see CurlCommand.
+// The body is read from the request, so changes by RequestModifiers, like WithCSRFFormField, are shown as sent.
+func (rh *RequestHandler) Curl() string {
+	body, err := requestBody(rh.spec.Request)
+	if err != nil {
+		body = rh.spec.Body
+	}
+	return CurlCommand(rh.spec.Request, body)
+}
+
+// shellQuote wraps s in single quotes, escaping contained single quotes.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// curlRequest holds everything parsed from a curl command line.
+type curlRequest struct {
+	method   string
+	url      string
+	header   http.Header
+	data     []string
+	jsonData []string
+	form     []curlFormField
+	user     string
+	get      bool
+	head     bool
+}
+
+// curlFormField is a single -F or --form-string argument.
+type curlFormField struct {
+	arg     string
+	literal bool
+}
+
+// curl flags that take a value and are translated into the request.
+var curlValueFlags = map[string]string{
+	"-X": "request", "--request": "request",
+	"-H": "header", "--header": "header",
+	"-d": "data", "--data": "data", "--data-ascii": "data",
+	"--data-raw": "data-raw", "--data-binary": "data-binary", "--data-urlencode": "data-urlencode",
+	"--json": "json",
+	"-u":     "user", "--user": "user",
+	"-F": "form", "--form": "form", "--form-string": "form-string",
+	"-A": "user-agent", "--user-agent": "user-agent",
+	"-e": "referer", "--referer": "referer",
+	"-b": "cookie", "--cookie": "cookie",
+	"-r": "range", "--range": "range",
+	"--url": "url",
+}
+
+// curl flags that take a value but have no influence on the request itself.
+var curlIgnoredValueFlags = map[string]bool{
+	"-o": true, "--output": true, "-w": true, "--write-out": true,
+	"-m": true, "--max-time": true, "--connect-timeout": true, "--retry": true,
+	"-c": true, "--cookie-jar": true, "--cacert": true, "--cert": true, "--key": true,
+	"-x": true, "--proxy": true, "--resolve": true,
+}
+
+// curl flags without a value.
+var curlBoolFlags = map[string]string{
+	"-G": "get", "--get": "get",
+	"-I": "head", "--head": "head",
+	"--compressed": "compressed",
+	"-s":           "", "--silent": "", "-S": "", "--show-error": "", "-v": "", "--verbose": "",
+	"-i": "", "--include": "", "-L": "", "--location": "", "-k": "", "--insecure": "",
+	"-f": "", "--fail": "", "--fail-with-body": "", "-N": "", "--no-buffer": "",
+	"-g": "", "--globoff": "", "--http1.1": "", "--http2": "", "-#": "", "--progress-bar": "",
+}
+
+func parseCurl(cmd string) (*curlRequest, error) {
+	args, err := splitShellWords(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("parsing curl command failed: %w", err)
+	}
+	if len(args) == 0 || args[0] != "curl" {
+		return nil, fmt.Errorf("parsing curl command failed: command does not start with curl")
+	}
+	cr := &curlRequest{header: http.Header{}}
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			if cr.url != "" {
+				return nil, fmt.Errorf("parsing curl command failed: multiple urls are not supported: %s", arg)
+			}
+			cr.url = arg
+			continue
+		}
+		flag, value, hasValue := arg, "", false
+		// short flags may carry their value directly, e.g. -XPOST
+		if !strings.HasPrefix(arg, "--") && len(arg) > 2 {
+			flag, value, hasValue = arg[:2], arg[2:], true
+		}
+		if name, ok := curlBoolFlags[flag]; ok {
+			if hasValue {
+				// combined short flags like -sSL
+				for _, c := range value {
+					if _, ok := curlBoolFlags["-"+string(c)]; !ok {
+						return nil, fmt.Errorf("parsing curl command failed: unsupported flag combination %s", arg)
+					}
+					cr.setBool(curlBoolFlags["-"+string(c)])
+				}
+			}
+			cr.setBool(name)
+			continue
+		}
+		name, isValueFlag := curlValueFlags[flag]
+		if !isValueFlag && !curlIgnoredValueFlags[flag] {
+			return nil, fmt.Errorf("parsing curl command failed: unsupported flag %s", flag)
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("parsing curl command failed: flag %s is missing its value", flag)
+			}
+			i++
+			value = args[i]
+		}
+		if !isValueFlag {
+			continue
+		}
+		if err := cr.setValue(name, value); err != nil {
+			return nil, fmt.Errorf("parsing curl command failed: %w", err)
+		}
+	}
+	if cr.url == "" {
+		return nil, fmt.Errorf("parsing curl command failed: no url given")
+	}
+	if !strings.Contains(cr.url, "://") {
+		cr.url = "http://" + cr.url
+	}
+	return cr, nil
+}
+
+func (cr *curlRequest) setBool(name string) {
+	switch name {
+	case "get":
+		cr.get = true
+	case "head":
+		cr.head = true
+	}
+	// --compressed needs no handling, the http.Transport negotiates and decodes gzip on its own.
+}
+
+func (cr *curlRequest) setValue(name, value string) error {
+	switch name {
+	case "request":
+		cr.method = value
+	case "url":
+		cr.url = value
+	case "header":
+		k, v, ok := strings.Cut(value, ":")
+		if !ok {
+			// "Name;" sends the header with an empty value
+			k, ok = strings.CutSuffix(value, ";")
+			if !ok {
+				return fmt.Errorf("invalid header %q", value)
+			}
+		}
+		cr.header.Add(strings.TrimSpace(k), strings.TrimSpace(v))
+	case "data", "data-binary":
+		if strings.HasPrefix(value, "@") {
+			b, err := os.ReadFile(value[1:])
+			if err != nil {
+				return err
+			}
+			value = string(b)
+			if name == "data" {
+				value = strings.NewReplacer("\r", "", "\n", "").Replace(value)
+			}
+		}
+		cr.data = append(cr.data, value)
+	case "data-raw":
+		cr.data = append(cr.data, value)
+	case "data-urlencode":
+		k, v, ok := strings.Cut(value, "=")
+		if !ok {
+			cr.data = append(cr.data, url.QueryEscape(value))
+		} else if k == "" {
+			cr.data = append(cr.data, url.QueryEscape(v))
+		} else {
+			cr.data = append(cr.data, k+"="+url.QueryEscape(v))
+		}
+	case "json":
+		cr.jsonData = append(cr.jsonData, value)
+	case "user":
+		cr.user = value
+	case "form":
+		cr.form = append(cr.form, curlFormField{arg: value})
+	case "form-string":
+		cr.form = append(cr.form, curlFormField{arg: value, literal: true})
+	case "user-agent":
+		cr.header.Set("User-Agent", value)
+	case "referer":
+		cr.header.Set("Referer", value)
+	case "cookie":
+		if !strings.Contains(value, "=") {
+			return fmt.Errorf("reading cookies from file %s is not supported", value)
+		}
+		cr.header.Add("Cookie", value)
+	case "range":
+		cr.header.Set("Range", "bytes="+value)
+	}
+	return nil
+}
+
+// specOpts translates the parsed command into SpecOptions.
+func (cr *curlRequest) specOpts() []SpecOption {
+	method := http.MethodGet
+	u := cr.url
+	var body []byte
+	switch {
+	case len(cr.form) > 0:
+		method = http.MethodPost
+	case len(cr.jsonData) > 0:
+		method = http.MethodPost
+		body = []byte(strings.Join(cr.jsonData, ""))
+	case len(cr.data) > 0 && cr.get:
+		sep := "?"
+		if strings.Contains(u, "?") {
+			sep = "&"
+		}
+		u += sep + strings.Join(cr.data, "&")
+	case len(cr.data) > 0:
+		method = http.MethodPost
+		body = []byte(strings.Join(cr.data, "&"))
+	case cr.head:
+		method = http.MethodHead
+	}
+	if cr.method != "" {
+		method = cr.method
+	}
+	opts := []SpecOption{WithMethod(method), WithUrl(u)}
+	if len(cr.form) > 0 {
+		opts = append(opts, cr.multipartBody())
+	} else if body != nil {
+		opts = append(opts, WithBody(body))
+	}
+	return opts
+}
+
+// multipartBody builds the multipart/form-data body from the -F arguments.
+// The Content-Type with the boundary is set on the spec, so every request built from it matches its own body.
+func (cr *curlRequest) multipartBody() SpecOption {
+	return func(rs *Spec) error {
+		var buf bytes.Buffer
+		w := multipart.NewWriter(&buf)
+		for _, f := range cr.form {
+			if err := writeCurlFormPart(w, f.arg, f.literal); err != nil {
+				return fmt.Errorf("spec option curl form failed: %s", err.Error())
+			}
+		}
+		if err := w.Close(); err != nil {
+			return fmt.Errorf("spec option curl form failed: %s", err.Error())
+		}
+		rs.Header.Set("Content-Type", w.FormDataContentType())
+		rs.Body = buf.Bytes()
+		return nil
+	}
+}
+
+// writeCurlFormPart writes a single -F argument, which is either name=value, name=@file or name=<file,
+// optionally followed by ;type= and ;filename= attributes.
+func writeCurlFormPart(w *multipart.Writer, arg string, literal bool) error {
+	name, value, ok := strings.Cut(arg, "=")
+	if !ok {
+		return fmt.Errorf("invalid form field %q", arg)
+	}
+	if literal || !strings.HasPrefix(value, "@") && !strings.HasPrefix(value, "<") {
+		return w.WriteField(name, value)
+	}
+	attrs := strings.Split(value[1:], ";")
+	path := attrs[0]
+	contentType, filename := "", filepath.Base(path)
+	for _, a := range attrs[1:] {
+		k, v, _ := strings.Cut(a, "=")
+		switch strings.TrimSpace(k) {
+		case "type":
+			contentType = v
+		case "filename":
+			filename = v
+		}
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if value[0] == '<' {
+		return w.WriteField(name, string(b))
+	}
+	return writeFilePart(w, name, filename, contentType, b)
+}
+
+// requestMods translates the parsed headers and credentials into RequestModifiers.
+func (cr *curlRequest) requestMods() []RequestModifier {
+	mods := []RequestModifier{
+		func(r *http.Request) error {
+			if len(cr.data) > 0 && len(cr.form) == 0 && !cr.get && cr.header.Get("Content-Type") == "" {
+				r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			}
+			if len(cr.jsonData) > 0 {
+				r.Header.Set("Content-Type", ContentHeaderJSON)
+				r.Header.Set("Accept", ContentHeaderJSON)
+			}
+			for k, v := range cr.header {
+				r.Header[k] = slices.Clone(v)
+			}
+			return nil
+		},
+	}
+	if cr.user != "" {
+		mods = append(mods, func(r *http.Request) error {
+			user, pass, _ := strings.Cut(cr.user, ":")
+			r.SetBasicAuth(user, pass)
+			return nil
+		})
+	}
+	return mods
+}
+
+// splitShellWords splits a command line like a POSIX shell would, honouring quotes, escapes and line continuations.
+func splitShellWords(s string) ([]string, error) {
+	var (
+		words   []string
+		word    strings.Builder
+		inWord  bool
+		runes   = []rune(s)
+		quote   rune
+		ansiC   bool
+		escaped bool
+	)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case escaped:
+			escaped = false
+			if r != '\n' {
+				word.WriteRune(r)
+				inWord = true
+			}
+		case quote == '\'' && ansiC:
+			if r == '\'' {
+				quote, ansiC = 0, false
+			} else if r == '\\' && i+1 < len(runes) {
+				i++
+				word.WriteString(ansiCEscape(runes[i]))
+			} else {
+				word.WriteRune(r)
+			}
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else if r == '\\' && i+1 < len(runes) && strings.ContainsRune("\"\\$`\n", runes[i+1]) {
+				i++
+				if runes[i] != '\n' {
+					word.WriteRune(runes[i])
+				}
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\\':
+			escaped = true
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case r == '$' && i+1 < len(runes) && runes[i+1] == '\'':
+			i++
+			quote, ansiC, inWord = '\'', true, true
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote %c", quote)
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// ansiCEscape resolves the escape sequences of $'...' strings.
+func ansiCEscape(r rune) string {
+	switch r {
+	case 'n':
+		return "\n"
+	case 't':
+		return "\t"
+	case 'r':
+		return "\r"
+	default:
+		return string(r)
+	}
+}
@@ -0,0 +1,213 @@
+package goe2e_test
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	goe2e "github.com/J-Bockhofer/goe2e/pkg"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpecFromCurl(t *testing.T) {
+	type expected struct {
+		method  string
+		url     string
+		body    string
+		headers goe2e.D
+	}
+
+	testCases := []struct {
+		name string
+		cmd  string
+		expected
+	}{
+		{"Plain GET", `curl https://example.com/persons`, expected{
+			http.MethodGet, "https://example.com/persons", "", goe2e.D{},
+		}},
+		{"Scheme defaults to http", `curl localhost:8080/ping`, expected{
+			http.MethodGet, "http://localhost:8080/ping", "", goe2e.D{},
+		}},
+		{"Method and headers", `curl -X PUT -H 'Accept: application/json' -H "X-Trace: a b" https://example.com`, expected{
+			http.MethodPut, "https://example.com", "", goe2e.D{"Accept": "application/json", "X-Trace": "a b"},
+		}},
+		{"Attached short flag value", `curl -XDELETE https://example.com/persons/1`, expected{
+			http.MethodDelete, "https://example.com/persons/1", "", goe2e.D{},
+		}},
+		{"Data implies POST", `curl -d 'name=john' -d age=32 https://example.com`, expected{
+			http.MethodPost, "https://example.com", "name=john&age=32", goe2e.D{"Content-Type": "application/x-www-form-urlencoded"},
+		}},
+		{"Data raw with explicit content type", `curl --data-raw '{"name":"john"}' -H 'Content-Type: application/json' https://example.com`, expected{
+			http.MethodPost, "https://example.com", `{"name":"john"}`, goe2e.D{"Content-Type": "application/json"},
+		}},
+		{"Data urlencode", `curl --data-urlencode 'q=a b&c' https://example.com`, expected{
+			http.MethodPost, "https://example.com", "q=a+b%26c", goe2e.D{},
+		}},
+		{"Get moves data to query", `curl -G -d q=go https://example.com/search`, expected{
+			http.MethodGet, "https://example.com/search?q=go", "", goe2e.D{},
+		}},
+		{"JSON flag", `curl --json '{"name":"john"}' https://example.com`, expected{
+			http.MethodPost, "https://example.com", `{"name":"john"}`, goe2e.D{"Content-Type": goe2e.ContentHeaderJSON, "Accept": goe2e.ContentHeaderJSON},
+		}},
+		{"Basic auth", `curl -u john:secret https://example.com`, expected{
+			http.MethodGet, "https://example.com", "", goe2e.D{"Authorization": "Basic am9objpzZWNyZXQ="},
+		}},
+		{"Ignored flags and continuation", "curl -sSL --compressed \\\n  -o out.json --url https://example.com", expected{
+			http.MethodGet, "https://example.com", "", goe2e.D{},
+		}},
+		{"Head", `curl -I https://example.com`, expected{
+			http.MethodHead, "https://example.com", "", goe2e.D{},
+		}},
+		{"ANSI-C quoting", `curl --data-raw $'a\nb' https://example.com`, expected{
+			http.MethodPost, "https://example.com", "a\nb", goe2e.D{},
+		}},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, mods, err := goe2e.SpecFromCurl(tt.cmd)
+			if !assert.NoError(t, err) {
+				return
+			}
+			for _, mod := range mods {
+				assert.NoError(t, mod(spec.Request))
+			}
+			assert.Equal(t, tt.method, spec.Method)
+			assert.Equal(t, tt.url, spec.Url)
+			assert.Equal(t, tt.body, string(spec.Body))
+			for k, v := range tt.headers {
+				assert.Equal(t, v, spec.Request.Header.Get(k))
+			}
+		})
+	}
+}
+
+func TestSpecFromCurlErrors(t *testing.T) {
+	testCases := []struct {
+		name string
+		cmd  string
+	}{
+		{"Not curl", `wget https://example.com`},
+		{"No url", `curl -X POST`},
+		{"Missing value", `curl https://example.com -H`},
+		{"Unsupported flag", `curl --proto-default https https://example.com`},
+		{"Unterminated quote", `curl -H 'Accept: */* https://example.com`},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := goe2e.SpecFromCurl(tt.cmd)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestSpecFromCurlForm(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "avatar.txt")
+	if err := os.WriteFile(path, []byte("file content"), 0o600); err != nil {
+		t.Fatalf("could not write test file: %s", err.Error())
+	}
+	spec, mods, err := goe2e.SpecFromCurl(`curl -F name=john -F "avatar=@` + path + `;type=text/plain" https://example.com/upload`)
+	if !assert.NoError(t, err) {
+		return
+	}
+	for _, mod := range mods {
+		assert.NoError(t, mod(spec.Request))
+	}
+	assert.Equal(t, http.MethodPost, spec.Method)
+	assert.True(t, strings.HasPrefix(spec.Request.Header.Get("Content-Type"), "multipart/form-data; boundary="))
+
+	err = spec.Request.ParseMultipartForm(1 << 20)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "john", spec.Request.FormValue("name"))
+	file, header, err := spec.Request.FormFile("avatar")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer file.Close()
+	content, _ := io.ReadAll(file)
+	assert.Equal(t, "avatar.txt", header.Filename)
+	assert.Equal(t, "text/plain", header.Header.Get("Content-Type"))
+	assert.Equal(t, "file content", string(content))
+
+	t.Run("Reused config", func(t *testing.T) {
+		tc, err := goe2e.TestConfigFromCurl("upload", `curl -F name=john https://example.com/upload`)
+		if !assert.NoError(t, err) {
+			return
+		}
+		first, err := goe2e.NewRequestHandler(goe2e.WithSpecOpts(tc.SpecOpts...))
+		if !assert.NoError(t, err) {
+			return
+		}
+		// building a second request must not change the boundary of the first
+		_, err = goe2e.NewRequestHandler(goe2e.WithSpecOpts(tc.SpecOpts...))
+		if !assert.NoError(t, err) || !assert.NoError(t, first.ModifyRequest(tc.RequestMods...)) {
+			return
+		}
+		if assert.NoError(t, first.GetRequest().ParseMultipartForm(1<<20)) {
+			assert.Equal(t, "john", first.GetRequest().FormValue("name"))
+		}
+	})
+}
+
+func TestTestConfigFromCurl(t *testing.T) {
+	tc, err := goe2e.TestConfigFromCurl("reported bug", `curl -X POST --json '{"name":"john"}' https://example.com/persons`)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "reported bug", tc.Name)
+	rh, err := goe2e.NewRequestHandler(goe2e.WithSpecOpts(tc.SpecOpts...))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NoError(t, rh.ModifyRequest(tc.RequestMods...))
+	assert.Equal(t, http.MethodPost, rh.GetRequest().Method)
+	assert.Equal(t, goe2e.ContentHeaderJSON, rh.GetRequest().Header.Get("Content-Type"))
+
+	t.Run("Interpolated headers", func(t *testing.T) {
+		tc, err := goe2e.TestConfigFromCurl("reused", `curl -H 'X-Token: {{token}}' https://example.com/persons`)
+		if !assert.NoError(t, err) {
+			return
+		}
+		// interpolating the headers of one run must not change the parsed headers used by the next
+		for _, token := range []string{"first", "second"} {
+			rh, err := goe2e.NewRequestHandler(goe2e.WithSpecOpts(tc.SpecOpts...))
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.NoError(t, rh.ModifyRequest(append(tc.RequestMods, goe2e.WithInterpolatedHeaders(goe2e.H{"token": token}))...))
+			assert.Equal(t, token, rh.GetRequest().Header.Get("X-Token"))
+		}
+	})
+}
+
+func TestCurlCommand(t *testing.T) {
+	env := goe2e.NewEnv()
+	env.SetSecret("apiKey", "curl-secret-key")
+	spec, err := goe2e.NewSpec(
+		goe2e.WithMethod(http.MethodPost),
+		goe2e.WithUrl("https://example.com/persons?key=curl-secret-key"),
+		goe2e.WithBody([]byte(`{"name":"o'neil"}`)),
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+	spec.Request.Header.Set("Authorization", "Bearer abcdefg")
+	spec.Request.Header.Set("Content-Type", goe2e.ContentHeaderJSON)
+
+	cmd := goe2e.CurlCommand(spec.Request, spec.Body)
+	assert.Equal(t, `curl -X POST 'https://example.com/persons?key=[REDACTED]' -H 'Authorization: [REDACTED]' -H 'Content-Type: application/json' --data-raw '{"name":"o'\''neil"}'`, cmd)
+
+	parsed, mods, err := goe2e.SpecFromCurl(cmd)
+	assert.NoError(t, err)
+	for _, mod := range mods {
+		assert.NoError(t, mod(parsed.Request))
+	}
+	assert.Equal(t, spec.Body, parsed.Body)
+	assert.Equal(t, goe2e.ContentHeaderJSON, parsed.Request.Header.Get("Content-Type"))
+}
@@ -0,0 +1,80 @@
+package goe2e
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// DecodeOption configures the json.Decoder used by DecodeResponse.
+type DecodeOption func(*decodeConfig)
+
+type decodeConfig struct {
+	strict    bool
+	useNumber bool
+}
+
+// Strict makes decoding fail on fields not present in the target type and on trailing data after the JSON value.
+func Strict() DecodeOption {
+	return func(dc *decodeConfig) {
+		dc.strict = true
+	}
+}
+
+// UseNumber decodes numbers in interface{} values as json.Number instead of float64.
+func UseNumber() DecodeOption {
+	return func(dc *decodeConfig) {
+		dc.useNumber = true
+	}
+}
+
+// DecodeResponse decodes the JSON ResponseBody into a value of type T, like a domain struct.
+func DecodeResponse[T any](rh *RequestHandler, opts ...DecodeOption) (T, error) {
+	return decodeJSON[T](rh.ResponseBody, opts...)
+}
+
+// TestDecoded decodes the JSON response into a value of type T and passes it to the statement.
+// The test fails without running the statement if the body can not be decoded.
+func TestDecoded[T any](statement func(*testing.T, T), opts ...DecodeOption) func(*testing.T, *RequestHandler) {
+	return func(t *testing.T, rh *RequestHandler) {
+		v, err := DecodeResponse[T](rh, opts...)
+		if err != nil {
+			if encErr := unsupportedEncoding(rh.Response); encErr != nil {
+				assert.Fail(t, err.Error(), "response body was not decoded: %s", encErr)
+				return
+			}
+			assert.Fail(t, err.Error())
+			return
+		}
+		statement(t, v)
+	}
+}
+
+func decodeJSON[T any](body []byte, opts ...DecodeOption) (T, error) {
+	var cfg decodeConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	var v T
+	dec := json.NewDecoder(bytes.NewReader(body))
+	if cfg.strict {
+		dec.DisallowUnknownFields()
+	}
+	if cfg.useNumber {
+		dec.UseNumber()
+	}
+	if err := dec.Decode(&v); err != nil {
+		return v, fmt.Errorf("decoding response into %T failed - json.Decode: %w", v, err)
+	}
+	if cfg.strict {
+		if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+			return v, fmt.Errorf("decoding response into %T failed: trailing data after JSON value", v)
+		}
+	}
+	return v, nil
+}
@@ -0,0 +1,88 @@
+package goe2e_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	goe2e "github.com/J-Bockhofer/goe2e/pkg"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type person struct {
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+func TestDecodeResponse(t *testing.T) {
+	testCases := []struct {
+		name     string
+		body     string
+		opts     []goe2e.DecodeOption
+		expected person
+		err      bool
+	}{
+		{"Lenient", `{"name":"john","age":32,"nickname":"jj"}`, nil, person{"john", 32}, false},
+		{"Strict", `{"name":"john","age":32}`, []goe2e.DecodeOption{goe2e.Strict()}, person{"john", 32}, false},
+		{"Strict unknown field", `{"name":"john","age":32,"nickname":"jj"}`, []goe2e.DecodeOption{goe2e.Strict()}, person{}, true},
+		{"Strict trailing data", `{"name":"john"} {"name":"jane"}`, []goe2e.DecodeOption{goe2e.Strict()}, person{}, true},
+		{"Wrong type", `{"name":"john","age":"32"}`, nil, person{}, true},
+		{"Invalid", `not json`, nil, person{}, true},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			rh := &goe2e.RequestHandler{ResponseBody: []byte(tt.body)}
+			p, err := goe2e.DecodeResponse[person](rh, tt.opts...)
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, p)
+		})
+	}
+
+	t.Run("UseNumber", func(t *testing.T) {
+		rh := &goe2e.RequestHandler{ResponseBody: []byte(`{"id":12345678901234567890}`)}
+		m, err := goe2e.DecodeResponse[goe2e.H](rh, goe2e.UseNumber())
+		assert.NoError(t, err)
+		assert.Equal(t, json.Number("12345678901234567890"), m["id"])
+	})
+}
+
+func TestDecoded(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p person
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(p)
+	}))
+	defer srv.Close()
+
+	goe2e.TestRequest(t, &goe2e.TestConfig{
+		Name: "POST /persons",
+		SpecOpts: []goe2e.SpecOption{
+			goe2e.WithMethod(http.MethodPost),
+			goe2e.WithUrl(srv.URL),
+			goe2e.WithJSON(person{Name: "john", Age: 32}),
+		},
+		PostTestStatements: []goe2e.TestStatement{
+			{Description: "status 202", Statement: goe2e.TestStatusCode(http.StatusAccepted)},
+			{Description: "person", Statement: goe2e.TestDecoded(func(t *testing.T, p person) {
+				assert.Equal(t, person{Name: "john", Age: 32}, p)
+			}, goe2e.Strict())},
+			{Description: "decode failure", Statement: func(t *testing.T, rh *goe2e.RequestHandler) {
+				mock := &testing.T{}
+				called := false
+				goe2e.TestDecoded(func(t *testing.T, p []person) { called = true })(mock, rh)
+				assert.True(t, mock.Failed())
+				assert.False(t, called)
+			}},
+		},
+	})
+}
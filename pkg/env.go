@@ -0,0 +1,407 @@
+package goe2e
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Env is a typed and scoped store for the variables shared between requests.
+// Scopes form a chain, e.g. global -> suite -> scenario -> step.
+// Lookups walk from the scope they are called on outwards, writes always go to the scope they are called on.
+// All changes are recorded in a history shared by the whole chain, to trace where a value came from.
+// Values of keys marked as secret are registered with the DefaultSecrets and redacted in the history.
+type Env struct {
+	name    string
+	parent  *Env
+	mu      sync.RWMutex
+	vars    H
+	history *envHistory
+}
+
+// EnvChange is a single recorded write to an Env.
+type EnvChange struct {
+	Scope  string
+	Key    string
+	Old    any
+	New    any
+	Source string
+	Time   time.Time
+}
+
+type envHistory struct {
+	mu         sync.Mutex
+	changes    []EnvChange
+	secretKeys map[string]bool
+}
+
+// NewEnv creates the global scope of a new Env.
+func NewEnv() *Env {
+	return &Env{
+		name:    "global",
+		vars:    H{},
+		history: &envHistory{secretKeys: map[string]bool{}},
+	}
+}
+
+// EnvFromMap creates the global scope of a new Env holding the values of vars.
+func EnvFromMap(vars H) *Env {
+	e := NewEnv()
+	for k, v := range vars {
+		e.set(k, v, "map")
+	}
+	return e
+}
+
+// Scope creates a child scope of e.
+// Values set in the child shadow those of its parents and are discarded together with the child.
+func (e *Env) Scope(name string) *Env {
+	return &Env{
+		name:    name,
+		parent:  e,
+		vars:    H{},
+		history: e.history,
+	}
+}
+
+// Name returns the name of the scope.
+func (e *Env) Name() string {
+	return e.name
+}
+
+// Parent returns the enclosing scope or nil for the global scope.
+func (e *Env) Parent() *Env {
+	return e.parent
+}
+
+// Lookup returns the value stored under key in the closest scope that defines it.
+func (e *Env) Lookup(key string) (any, bool) {
+	for s := e; s != nil; s = s.parent {
+		s.mu.RLock()
+		v, ok := s.vars[key]
+		s.mu.RUnlock()
+		if ok {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// Has reports whether key is defined in e or one of its parents.
+func (e *Env) Has(key string) bool {
+	_, ok := e.Lookup(key)
+	return ok
+}
+
+// Set stores val under key in the scope e.
+func (e *Env) Set(key string, val any) {
+	e.set(key, val, "set")
+}
+
+// SetSecret stores val under key in the scope e and marks the key as secret in all scopes.
+// The value is registered with the DefaultSecrets, so it is redacted from failure messages and logs.
+func (e *Env) SetSecret(key string, val string) {
+	e.history.mu.Lock()
+	e.history.secretKeys[key] = true
+	e.history.mu.Unlock()
+	e.set(key, val, "secret")
+}
+
+// SetSecretFromFile reads a secret with SecretFromFile and stores it under key, see (*Env).SetSecret.
+func (e *Env) SetSecretFromFile(key string, path string) error {
+	secret, err := SecretFromFile(path)
+	if err != nil {
+		return err
+	}
+	e.SetSecret(key, secret)
+	return nil
+}
+
+// SetSecretFromOSEnv reads a secret with SecretFromOSEnv and stores it under key, see (*Env).SetSecret.
+func (e *Env) SetSecretFromOSEnv(key string, name string) error {
+	secret, err := SecretFromOSEnv(name)
+	if err != nil {
+		return err
+	}
+	e.SetSecret(key, secret)
+	return nil
+}
+
+// IsSecret reports whether key was marked as secret.
+func (e *Env) IsSecret(key string) bool {
+	e.history.mu.Lock()
+	defer e.history.mu.Unlock()
+	return e.history.secretKeys[key]
+}
+
+// Delete removes key from the scope e. Values of the parent scopes are left untouched.
+func (e *Env) Delete(key string) {
+	e.mu.Lock()
+	old, ok := e.vars[key]
+	delete(e.vars, key)
+	e.mu.Unlock()
+	if ok {
+		e.record(key, old, nil, "delete")
+	}
+}
+
+// Keys returns all keys visible from e, sorted.
+func (e *Env) Keys() []string {
+	m := e.Map()
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Map returns a flattened copy of all values visible from e.
+func (e *Env) Map() H {
+	var chain []*Env
+	for s := e; s != nil; s = s.parent {
+		chain = append(chain, s)
+	}
+	m := H{}
+	for i := len(chain) - 1; i >= 0; i-- {
+		chain[i].mu.RLock()
+		for k, v := range chain[i].vars {
+			m[k] = v
+		}
+		chain[i].mu.RUnlock()
+	}
+	return m
+}
+
+// History returns all recorded changes of the Env, across all scopes, in the order they happened.
+func (e *Env) History() []EnvChange {
+	e.history.mu.Lock()
+	defer e.history.mu.Unlock()
+	return append([]EnvChange(nil), e.history.changes...)
+}
+
+// String returns the value under key as a string. Numbers and booleans are formatted.
+func (e *Env) String(key string) (string, error) {
+	v, ok := e.Lookup(key)
+	if !ok {
+		return "", fmt.Errorf("env key %s not found", key)
+	}
+	switch t := v.(type) {
+	case string:
+		return t, nil
+	case json.Number:
+		return t.String(), nil
+	case int, int64, int32, float64, float32, bool:
+		return fmt.Sprint(t), nil
+	default:
+		return "", fmt.Errorf("env key %s is of type %T, not string", key, v)
+	}
+}
+
+// Int returns the value under key as an int. Whole floats, as produced by encoding/json, and numeric strings are converted.
+func (e *Env) Int(key string) (int, error) {
+	v, ok := e.Lookup(key)
+	if !ok {
+		return 0, fmt.Errorf("env key %s not found", key)
+	}
+	switch t := v.(type) {
+	case int:
+		return t, nil
+	case int64:
+		return int(t), nil
+	case int32:
+		return int(t), nil
+	case float64:
+		if t == float64(int(t)) {
+			return int(t), nil
+		}
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return int(i), nil
+		}
+	case string:
+		if i, err := strconv.Atoi(t); err == nil {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("env key %s with value %s is not an int", key, DefaultSecrets.Redact(fmt.Sprint(v)))
+}
+
+// Bool returns the value under key as a bool. Strings are parsed with strconv.ParseBool.
+func (e *Env) Bool(key string) (bool, error) {
+	v, ok := e.Lookup(key)
+	if !ok {
+		return false, fmt.Errorf("env key %s not found", key)
+	}
+	switch t := v.(type) {
+	case bool:
+		return t, nil
+	case string:
+		if b, err := strconv.ParseBool(t); err == nil {
+			return b, nil
+		}
+	}
+	return false, fmt.Errorf("env key %s with value %s is not a bool", key, DefaultSecrets.Redact(fmt.Sprint(v)))
+}
+
+// Get returns the value under key as type T. The stored value has to be of type T, no conversion takes place.
+func Get[T any](e *Env, key string) (T, error) {
+	var zero T
+	v, ok := e.Lookup(key)
+	if !ok {
+		return zero, fmt.Errorf("env key %s not found", key)
+	}
+	t, ok := v.(T)
+	if !ok {
+		return zero, fmt.Errorf("env key %s is of type %T, not %T", key, v, zero)
+	}
+	return t, nil
+}
+
+// LoadFile sets all top level values of a .env, JSON or YAML file in the scope e.
+// The format is chosen by the file extension, files without a known extension are read as .env files.
+func (e *Env) LoadFile(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("loading env file failed: %w", err)
+	}
+	var vars H
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(b, &vars)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(b, &vars)
+	default:
+		vars, err = parseDotEnv(b)
+	}
+	if err != nil {
+		return fmt.Errorf("loading env file %s failed: %w", path, err)
+	}
+	for k, v := range vars {
+		e.set(k, v, "file:"+path)
+	}
+	return nil
+}
+
+// LoadOSEnv sets all OS environment variables starting with prefix in the scope e.
+// The prefix is stripped from the keys, e.g. with the prefix "E2E_" the variable E2E_baseUrl is stored as baseUrl.
+func (e *Env) LoadOSEnv(prefix string) {
+	for _, kv := range os.Environ() {
+		k, v, _ := strings.Cut(kv, "=")
+		key, ok := strings.CutPrefix(k, prefix)
+		if !ok || key == "" {
+			continue
+		}
+		e.set(key, v, "os:"+k)
+	}
+}
+
+func (e *Env) set(key string, val any, source string) {
+	if s, ok := val.(string); ok && e.IsSecret(key) {
+		DefaultSecrets.Add(s)
+	}
+	e.mu.Lock()
+	old := e.vars[key]
+	e.vars[key] = val
+	e.mu.Unlock()
+	e.record(key, old, val, source)
+}
+
+func (e *Env) record(key string, old, val any, source string) {
+	e.history.mu.Lock()
+	defer e.history.mu.Unlock()
+	if e.history.secretKeys[key] {
+		if old != nil {
+			old = RedactedPlaceholder
+		}
+		if val != nil {
+			val = RedactedPlaceholder
+		}
+	}
+	e.history.changes = append(e.history.changes, EnvChange{
+		Scope:  e.name,
+		Key:    key,
+		Old:    old,
+		New:    val,
+		Source: source,
+		Time:   time.Now(),
+	})
+}
+
+// parseDotEnv reads KEY=VALUE lines, ignoring blank lines, comments and a leading "export".
+// Double quoted values support \n escapes, single quoted values are taken literally.
+func parseDotEnv(b []byte) (H, error) {
+	vars := H{}
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		k, v, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: missing '='", lineNo)
+		}
+		k, v = strings.TrimSpace(k), strings.TrimSpace(v)
+		switch {
+		case len(v) >= 2 && v[0] == '"' && v[len(v)-1] == '"':
+			v = strings.NewReplacer(`\n`, "\n", `\"`, `"`, `\\`, `\`).Replace(v[1 : len(v)-1])
+		case len(v) >= 2 && v[0] == '\'' && v[len(v)-1] == '\'':
+			v = v[1 : len(v)-1]
+		default:
+			if i := strings.Index(v, " #"); i >= 0 {
+				v = strings.TrimSpace(v[:i])
+			}
+		}
+		vars[k] = v
+	}
+	return vars, scanner.Err()
+}
+
+// envStore abstracts over a plain H map and an *Env, so the functions taking an H share their implementation with the Env methods.
+type envStore interface {
+	lookup(key string) (any, bool)
+	set(key string, val any, source string)
+	keys() []string
+}
+
+// mapEnv adapts a plain H map, looking up keys in nested maps like ValueInMapByKey.
+type mapEnv H
+
+func (m mapEnv) lookup(key string) (any, bool) {
+	v := ValueInMapByKey(key, H(m))
+	return v, v != nil
+}
+
+func (m mapEnv) set(key string, val any, _ string) {
+	m[key] = val
+}
+
+func (m mapEnv) keys() []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func (e *Env) lookup(key string) (any, bool) {
+	return e.Lookup(key)
+}
+
+func (e *Env) keys() []string {
+	return e.Keys()
+}
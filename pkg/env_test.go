@@ -0,0 +1,186 @@
+package goe2e_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	goe2e "github.com/J-Bockhofer/goe2e/pkg"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnvScopes(t *testing.T) {
+	global := goe2e.NewEnv()
+	global.Set("baseUrl", "http://localhost:8080")
+	global.Set("user", "admin")
+	suite := global.Scope("suite")
+	suite.Set("user", "john")
+	step := suite.Scope("step")
+	step.Set("id", 7)
+
+	t.Run("Lookup walks outwards", func(t *testing.T) {
+		v, err := step.String("baseUrl")
+		assert.NoError(t, err)
+		assert.Equal(t, "http://localhost:8080", v)
+	})
+	t.Run("Child shadows parent", func(t *testing.T) {
+		v, err := step.String("user")
+		assert.NoError(t, err)
+		assert.Equal(t, "john", v)
+		v, err = global.String("user")
+		assert.NoError(t, err)
+		assert.Equal(t, "admin", v)
+	})
+	t.Run("Parent does not see child", func(t *testing.T) {
+		assert.False(t, suite.Has("id"))
+	})
+	t.Run("Keys and Map", func(t *testing.T) {
+		assert.Equal(t, []string{"baseUrl", "id", "user"}, step.Keys())
+		assert.Equal(t, goe2e.H{"baseUrl": "http://localhost:8080", "user": "john", "id": 7}, step.Map())
+	})
+	t.Run("History", func(t *testing.T) {
+		history := global.History()
+		assert.Len(t, history, 4)
+		assert.Equal(t, "suite", history[2].Scope)
+		assert.Equal(t, "user", history[2].Key)
+		assert.Equal(t, "john", history[2].New)
+		assert.Equal(t, "set", history[2].Source)
+	})
+}
+
+func TestEnvTypedGetters(t *testing.T) {
+	env := goe2e.EnvFromMap(goe2e.H{
+		"name":       "john",
+		"age":        32,
+		"jsonAge":    float64(24),
+		"strAge":     "41",
+		"fraction":   1.5,
+		"active":     true,
+		"strActive":  "false",
+		"tags":       []string{"a", "b"},
+		"jsonNumber": json.Number("12"),
+	})
+
+	testCases := []struct {
+		name     string
+		get      func() (any, error)
+		expected any
+		err      bool
+	}{
+		{"String", func() (any, error) { return env.String("name") }, "john", false},
+		{"String from int", func() (any, error) { return env.String("age") }, "32", false},
+		{"String missing", func() (any, error) { return env.String("missing") }, "", true},
+		{"Int", func() (any, error) { return env.Int("age") }, 32, false},
+		{"Int from float", func() (any, error) { return env.Int("jsonAge") }, 24, false},
+		{"Int from string", func() (any, error) { return env.Int("strAge") }, 41, false},
+		{"Int from json.Number", func() (any, error) { return env.Int("jsonNumber") }, 12, false},
+		{"Int from fraction", func() (any, error) { return env.Int("fraction") }, 0, true},
+		{"Bool", func() (any, error) { return env.Bool("active") }, true, false},
+		{"Bool from string", func() (any, error) { return env.Bool("strActive") }, false, false},
+		{"Bool from int", func() (any, error) { return env.Bool("age") }, false, true},
+		{"Get", func() (any, error) { return goe2e.Get[[]string](env, "tags") }, []string{"a", "b"}, false},
+		{"Get wrong type", func() (any, error) { return goe2e.Get[int](env, "name") }, 0, true},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := tt.get()
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestEnvLoad(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"test.env":   "# comment\nexport baseUrl=http://localhost:8080\nname=\"jo hn\"\nraw='a\\nb'\nplain=value # trailing\n",
+		"test.json":  `{"baseUrl":"http://json","age":32}`,
+		"test.yaml":  "baseUrl: http://yaml\nage: 41\nnested:\n  key: v\n",
+		"broken.env": "novalue\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatalf("could not write test file: %s", err.Error())
+		}
+	}
+
+	t.Run(".env", func(t *testing.T) {
+		env := goe2e.NewEnv()
+		assert.NoError(t, env.LoadFile(filepath.Join(dir, "test.env")))
+		assert.Equal(t, goe2e.H{"baseUrl": "http://localhost:8080", "name": "jo hn", "raw": `a\nb`, "plain": "value"}, env.Map())
+		assert.Equal(t, "file:"+filepath.Join(dir, "test.env"), env.History()[0].Source)
+	})
+	t.Run("JSON", func(t *testing.T) {
+		env := goe2e.NewEnv()
+		assert.NoError(t, env.LoadFile(filepath.Join(dir, "test.json")))
+		age, err := env.Int("age")
+		assert.NoError(t, err)
+		assert.Equal(t, 32, age)
+	})
+	t.Run("YAML", func(t *testing.T) {
+		env := goe2e.NewEnv()
+		assert.NoError(t, env.LoadFile(filepath.Join(dir, "test.yaml")))
+		age, err := env.Int("age")
+		assert.NoError(t, err)
+		assert.Equal(t, 41, age)
+		nested, err := goe2e.Get[goe2e.H](env, "nested")
+		assert.NoError(t, err)
+		assert.Equal(t, "v", nested["key"])
+	})
+	t.Run("Broken", func(t *testing.T) {
+		assert.Error(t, goe2e.NewEnv().LoadFile(filepath.Join(dir, "broken.env")))
+	})
+	t.Run("Missing", func(t *testing.T) {
+		assert.Error(t, goe2e.NewEnv().LoadFile(filepath.Join(dir, "missing.json")))
+	})
+	t.Run("OS env", func(t *testing.T) {
+		t.Setenv("GOE2E_TEST_baseUrl", "http://os")
+		env := goe2e.NewEnv()
+		env.LoadOSEnv("GOE2E_TEST_")
+		v, err := env.String("baseUrl")
+		assert.NoError(t, err)
+		assert.Equal(t, "http://os", v)
+	})
+}
+
+func TestEnvAdapters(t *testing.T) {
+	env := goe2e.NewEnv()
+	env.Set("baseUrl", "www.myurl.com")
+	scenario := env.Scope("scenario")
+	scenario.Set("personName", "jamie")
+
+	t.Run("WithBaseURLFromEnv", func(t *testing.T) {
+		spec, err := goe2e.NewSpec(scenario.WithBaseURLFromEnv("baseUrl", "persons"))
+		assert.NoError(t, err)
+		assert.Equal(t, "www.myurl.com/persons", spec.Url)
+	})
+	t.Run("WithSetFromEnv", func(t *testing.T) {
+		spec, err := goe2e.NewSpec(
+			goe2e.WithJSON(goe2e.H{"name": "john"}), scenario.WithSetFromEnv(goe2e.D{"personName": "name"}),
+		)
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"name":"jamie"}`, string(spec.Body))
+	})
+	t.Run("ResponseJSONToEnv", func(t *testing.T) {
+		step := scenario.Scope("step")
+		_, err := step.ResponseJSONToEnv(goe2e.D{"personName": "name"})([]byte(`{"name":"john"}`))
+		assert.NoError(t, err)
+		v, _ := step.String("personName")
+		assert.Equal(t, "john", v)
+		v, _ = scenario.String("personName")
+		assert.Equal(t, "jamie", v)
+		history := env.History()
+		assert.Equal(t, "response", history[len(history)-1].Source)
+	})
+	t.Run("Interpolate", func(t *testing.T) {
+		s, err := scenario.Interpolate("{{baseUrl}}/{{personName}}")
+		assert.NoError(t, err)
+		assert.Equal(t, "www.myurl.com/jamie", s)
+	})
+}
@@ -0,0 +1,208 @@
+package goe2e
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+const (
+	ContentHeaderForm string = "application/x-www-form-urlencoded"
+)
+
+// WithForm encodes the payload as application/x-www-form-urlencoded request body and sets the matching Content-Type.
+// The payload can be url.Values, a map with string keys or a struct.
+// Struct fields are named by their `form` tag, falling back to the `json` tag and the field name, and support "-" and "omitempty".
+// Slice values are sent as repeated fields.
+func WithForm(payload any) SpecOption {
+	return func(rs *Spec) error {
+		form, err := formValues(payload)
+		if err != nil {
+			return fmt.Errorf("spec option WithForm failed - %s", err.Error())
+		}
+		rs.Body = []byte(form.Encode())
+		rs.Header.Set("Content-Type", ContentHeaderForm)
+		return nil
+	}
+}
+
+// MultipartPart writes a single part of a multipart/form-data body.
+// Use one of the predefined FormField or FileFrom functions or define your own.
+type MultipartPart func(*multipart.Writer) error
+
+// WithMultipart builds a multipart/form-data request body from the parts and sets the matching Content-Type with boundary.
+func WithMultipart(parts ...MultipartPart) SpecOption {
+	return func(rs *Spec) error {
+		var buf bytes.Buffer
+		w := multipart.NewWriter(&buf)
+		for _, part := range parts {
+			if err := part(w); err != nil {
+				return fmt.Errorf("spec option WithMultipart failed - %s", err.Error())
+			}
+		}
+		if err := w.Close(); err != nil {
+			return fmt.Errorf("spec option WithMultipart failed - %s", err.Error())
+		}
+		rs.Body = buf.Bytes()
+		rs.Header.Set("Content-Type", w.FormDataContentType())
+		return nil
+	}
+}
+
+// FormField adds a plain form field to a multipart body.
+func FormField(name, value string) MultipartPart {
+	return func(w *multipart.Writer) error {
+		return w.WriteField(name, value)
+	}
+}
+
+// FileFromDisk uploads the file at path under the form field name, using the file's base name as filename.
+func FileFromDisk(field, path string) MultipartPart {
+	return func(w *multipart.Writer) error {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return writeFilePart(w, field, filepath.Base(path), "", b)
+	}
+}
+
+// FileFromBytes uploads content as file with the given filename under the form field name.
+func FileFromBytes(field, filename string, content []byte) MultipartPart {
+	return func(w *multipart.Writer) error {
+		return writeFilePart(w, field, filename, "", content)
+	}
+}
+
+// FileFromReader uploads everything read from r as file with the given filename under the form field name.
+func FileFromReader(field, filename string, r io.Reader) MultipartPart {
+	return func(w *multipart.Writer) error {
+		b, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		return writeFilePart(w, field, filename, "", b)
+	}
+}
+
+// FileWithContentType uploads content like FileFromBytes, but with an explicit Content-Type instead of a detected one.
+func FileWithContentType(field, filename, contentType string, content []byte) MultipartPart {
+	return func(w *multipart.Writer) error {
+		return writeFilePart(w, field, filename, contentType, content)
+	}
+}
+
+// writeFilePart writes a file part, detecting the Content-Type from the filename extension or the content if none is given.
+func writeFilePart(w *multipart.Writer, field, filename, contentType string, content []byte) error {
+	if contentType == "" {
+		contentType = mime.TypeByExtension(filepath.Ext(filename))
+	}
+	if contentType == "" {
+		contentType = http.DetectContentType(content)
+	}
+	h := textproto.MIMEHeader{}
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name=%q; filename=%q`, field, filename))
+	h.Set("Content-Type", contentType)
+	part, err := w.CreatePart(h)
+	if err != nil {
+		return err
+	}
+	_, err = part.Write(content)
+	return err
+}
+
+// formValues converts url.Values, maps with string keys and structs into url.Values.
+func formValues(payload any) (url.Values, error) {
+	switch t := payload.(type) {
+	case url.Values:
+		return t, nil
+	case nil:
+		return url.Values{}, nil
+	}
+	v := reflect.ValueOf(payload)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return url.Values{}, nil
+		}
+		v = v.Elem()
+	}
+	form := url.Values{}
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("unsupported map key type %s", v.Type().Key())
+		}
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		for _, k := range keys {
+			addFormValue(form, k.String(), v.MapIndex(k))
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, omitEmpty := formFieldName(field)
+			if name == "-" {
+				continue
+			}
+			if omitEmpty && v.Field(i).IsZero() {
+				continue
+			}
+			addFormValue(form, name, v.Field(i))
+		}
+	default:
+		return nil, fmt.Errorf("unsupported payload type %T", payload)
+	}
+	return form, nil
+}
+
+// formFieldName reads the name of a struct field from its form or json tag.
+func formFieldName(field reflect.StructField) (string, bool) {
+	tag, ok := field.Tag.Lookup("form")
+	if !ok {
+		tag = field.Tag.Get("json")
+	}
+	name, opts, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = field.Name
+	}
+	for _, opt := range strings.Split(opts, ",") {
+		if opt == "omitempty" {
+			return name, true
+		}
+	}
+	return name, false
+}
+
+// addFormValue adds a value to the form, slices as repeated fields.
+func addFormValue(form url.Values, key string, v reflect.Value) {
+	for v.Kind() == reflect.Interface || v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	if (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && v.Type().Elem().Kind() != reflect.Uint8 {
+		for i := 0; i < v.Len(); i++ {
+			addFormValue(form, key, v.Index(i))
+		}
+		return
+	}
+	if b, ok := v.Interface().([]byte); ok {
+		form.Add(key, string(b))
+		return
+	}
+	form.Add(key, fmt.Sprint(v.Interface()))
+}
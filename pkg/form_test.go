@@ -0,0 +1,114 @@
+package goe2e_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	goe2e "github.com/J-Bockhofer/goe2e/pkg"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithForm(t *testing.T) {
+	type signup struct {
+		Name     string   `form:"name"`
+		Email    string   `json:"email"`
+		Tags     []string `form:"tag"`
+		Age      int
+		Nickname string `form:"nickname,omitempty"`
+		Internal string `form:"-"`
+		private  string
+	}
+	testCases := []struct {
+		name     string
+		payload  any
+		expected string
+		err      bool
+	}{
+		{"url.Values", url.Values{"a": {"1", "2"}}, "a=1&a=2", false},
+		{"D", goe2e.D{"b": "2", "a": "x y"}, "a=x+y&b=2", false},
+		{"H", goe2e.H{"n": 1, "list": []any{true, "x"}, "nil": nil}, "list=true&list=x&n=1", false},
+		{"Struct", &signup{Name: "john", Email: "j@x.io", Tags: []string{"a", "b"}, Age: 3, Internal: "i", private: "p"},
+			"Age=3&email=j%40x.io&name=john&tag=a&tag=b", false},
+		{"Unsupported", 42, "", true},
+		{"Unsupported map key", map[int]string{1: "a"}, "", true},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, err := goe2e.NewSpec(goe2e.WithMethod(http.MethodPost), goe2e.WithForm(tt.payload))
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, tt.expected, string(spec.Body))
+			assert.Equal(t, goe2e.ContentHeaderForm, spec.Request.Header.Get("Content-Type"))
+		})
+	}
+}
+
+func TestWithMultipart(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		lines := []string{"title=" + r.FormValue("title")}
+		for _, k := range []string{"disk", "bytes", "reader", "typed"} {
+			f, h, err := r.FormFile(k)
+			if err != nil {
+				lines = append(lines, k+" missing")
+				continue
+			}
+			b, _ := io.ReadAll(f)
+			lines = append(lines, k+"="+h.Filename+";"+h.Header.Get("Content-Type")+";"+string(b))
+		}
+		w.Write([]byte(strings.Join(lines, "\n")))
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "report.json")
+	if !assert.NoError(t, os.WriteFile(path, []byte(`{"a":1}`), 0o600)) {
+		return
+	}
+
+	goe2e.TestRequest(t, &goe2e.TestConfig{
+		Name: "POST /upload",
+		SpecOpts: []goe2e.SpecOption{
+			goe2e.WithMethod(http.MethodPost),
+			goe2e.WithUrl(srv.URL),
+			goe2e.WithMultipart(
+				goe2e.FormField("title", "quarterly"),
+				goe2e.FileFromDisk("disk", path),
+				goe2e.FileFromBytes("bytes", "note.txt", []byte("hello")),
+				goe2e.FileFromReader("reader", "blob", strings.NewReader("\x89PNG\r\n\x1a\n")),
+				goe2e.FileWithContentType("typed", "data", "application/vnd.custom", []byte("{}")),
+			),
+		},
+		PostTestStatements: []goe2e.TestStatement{
+			{Description: "status 200", Statement: goe2e.TestStatusCode(http.StatusOK)},
+			{Description: "parts received", Statement: func(t *testing.T, rh *goe2e.RequestHandler) {
+				assert.Equal(t, strings.Join([]string{
+					"title=quarterly",
+					`disk=report.json;application/json;{"a":1}`,
+					"bytes=note.txt;text/plain; charset=utf-8;hello",
+					"reader=blob;image/png;\x89PNG\r\n\x1a\n",
+					"typed=data;application/vnd.custom;{}",
+				}, "\n"), string(rh.ResponseBody))
+			}},
+		},
+	})
+
+	t.Run("Missing file", func(t *testing.T) {
+		_, err := goe2e.NewSpec(goe2e.WithMultipart(goe2e.FileFromDisk("f", filepath.Join(t.TempDir(), "missing"))))
+		assert.Error(t, err)
+	})
+}
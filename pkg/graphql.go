@@ -0,0 +1,180 @@
+package goe2e
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// GraphQLRequest is the standard body of a GraphQL request sent via POST.
+type GraphQLRequest struct {
+	Query         string `json:"query"`
+	Variables     H      `json:"variables,omitempty"`
+	OperationName string `json:"operationName,omitempty"`
+}
+
+// GraphQLResponse separates the data of a GraphQL response from its errors.
+type GraphQLResponse struct {
+	Data       json.RawMessage `json:"data"`
+	Errors     []GraphQLError  `json:"errors"`
+	Extensions H               `json:"extensions"`
+}
+
+// GraphQLError is a single entry of the errors list of a GraphQL response.
+type GraphQLError struct {
+	Message    string            `json:"message"`
+	Path       []any             `json:"path"`
+	Locations  []GraphQLLocation `json:"locations"`
+	Extensions H                 `json:"extensions"`
+}
+
+type GraphQLLocation struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// String renders the error with its path and code, if present.
+func (e GraphQLError) String() string {
+	s := e.Message
+	if len(e.Path) > 0 {
+		path := make([]string, len(e.Path))
+		for i, p := range e.Path {
+			path[i] = fmt.Sprint(p)
+		}
+		s += " (path: " + strings.Join(path, ".") + ")"
+	}
+	if code, ok := e.Extensions["code"]; ok {
+		s += fmt.Sprintf(" [%v]", code)
+	}
+	return s
+}
+
+// WithGraphQL builds the POST request of a GraphQL operation. Variables and operationName are optional.
+func WithGraphQL(query string, variables H, operationName string) SpecOption {
+	return func(rs *Spec) error {
+		b, err := json.Marshal(GraphQLRequest{Query: query, Variables: variables, OperationName: operationName})
+		if err != nil {
+			return fmt.Errorf("spec option WithGraphQL failed - json.Marshal: %s", err.Error())
+		}
+		rs.Method = http.MethodPost
+		rs.Body = b
+		rs.Header.Set("Content-Type", ContentHeaderJSON)
+		rs.Header.Set("Accept", "application/graphql-response+json, "+ContentHeaderJSON)
+		return nil
+	}
+}
+
+// ParseGraphQLResponse parses a GraphQL response body.
+func ParseGraphQLResponse(body []byte) (*GraphQLResponse, error) {
+	var resp GraphQLResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("parsing graphql response failed - json.Unmarshal: %w", err)
+	}
+	return &resp, nil
+}
+
+// DataMap returns the data of the response as map, which is nil if the response has no data.
+func (gr *GraphQLResponse) DataMap() (H, error) {
+	if len(gr.Data) == 0 || string(gr.Data) == "null" {
+		return nil, nil
+	}
+	return bodyJSONToMap(gr.Data)
+}
+
+// Err joins all errors of the response into a single error, or returns nil if there are none.
+func (gr *GraphQLResponse) Err() error {
+	if len(gr.Errors) == 0 {
+		return nil
+	}
+	msgs := make([]string, len(gr.Errors))
+	for i, e := range gr.Errors {
+		msgs[i] = e.String()
+	}
+	return fmt.Errorf("graphql errors: %s", strings.Join(msgs, "; "))
+}
+
+// GraphQLDataToEnv is the GraphQL counterpart of ResponseJSONToEnv, reading the values from the data of the response.
+// It fails if the response contains any errors, as the data is incomplete then.
+func GraphQLDataToEnv(env H, keymap D) ResponseBodyModifier {
+	return graphQLDataToEnv(mapEnv(env), keymap)
+}
+
+// GraphQLDataToEnv is the Env counterpart of the GraphQLDataToEnv function, the values are set in the scope it is called on.
+func (e *Env) GraphQLDataToEnv(keymap D) ResponseBodyModifier {
+	return graphQLDataToEnv(e, keymap)
+}
+
+func graphQLDataToEnv(store envStore, keymap D) ResponseBodyModifier {
+	return func(body []byte) ([]byte, error) {
+		resp, err := ParseGraphQLResponse(body)
+		if err != nil {
+			return nil, err
+		}
+		if err := resp.Err(); err != nil {
+			return nil, err
+		}
+		if len(resp.Data) == 0 {
+			return body, nil
+		}
+		if _, err := responseJSONToEnv(store, keymap)(resp.Data); err != nil {
+			return nil, err
+		}
+		return body, nil
+	}
+}
+
+// TestGraphQLNoErrors asserts that the GraphQL response has data and no errors.
+// GraphQL servers usually answer with status 200 even if the operation failed, so checking the status code alone is not enough.
+func TestGraphQLNoErrors() func(*testing.T, *RequestHandler) {
+	return func(t *testing.T, rh *RequestHandler) {
+		resp, err := ParseGraphQLResponse(rh.ResponseBody)
+		if err != nil {
+			assert.Fail(t, err.Error())
+			return
+		}
+		if err := resp.Err(); err != nil {
+			assert.Fail(t, err.Error())
+		}
+		if len(resp.Data) == 0 || string(resp.Data) == "null" {
+			assert.Fail(t, "graphql response has no data")
+		}
+	}
+}
+
+// TestGraphQLError asserts that the GraphQL response has an error whose message contains the given text.
+func TestGraphQLError(contains string) func(*testing.T, *RequestHandler) {
+	return func(t *testing.T, rh *RequestHandler) {
+		resp, err := ParseGraphQLResponse(rh.ResponseBody)
+		if err != nil {
+			assert.Fail(t, err.Error())
+			return
+		}
+		for _, e := range resp.Errors {
+			if strings.Contains(e.Message, contains) {
+				return
+			}
+		}
+		assert.Fail(t, fmt.Sprintf("graphql response has no error containing %q", contains), "errors: %v", resp.Errors)
+	}
+}
+
+// TestGraphQLData asserts the value of a key in the data of the GraphQL response, found like in ResponseJSONToEnv.
+func TestGraphQLData(key string, expected any) func(*testing.T, *RequestHandler) {
+	return func(t *testing.T, rh *RequestHandler) {
+		resp, err := ParseGraphQLResponse(rh.ResponseBody)
+		if err != nil {
+			assert.Fail(t, err.Error())
+			return
+		}
+		data, err := resp.DataMap()
+		if err != nil {
+			assert.Fail(t, err.Error())
+			return
+		}
+		assert.EqualValues(t, expected, ValueInMapByKey(key, data), "data key %s", key)
+	}
+}
@@ -0,0 +1,98 @@
+package goe2e_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	goe2e "github.com/J-Bockhofer/goe2e/pkg"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newGraphQLServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req goe2e.GraphQLRequest
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != goe2e.ContentHeaderJSON ||
+			json.NewDecoder(r.Body).Decode(&req) != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", goe2e.ContentHeaderJSON)
+		if req.Variables["id"] != "1" {
+			w.Write([]byte(`{"data":{"person":null},"errors":[{"message":"person not found","path":["person"],"extensions":{"code":"NOT_FOUND"}}]}`))
+			return
+		}
+		w.Write([]byte(`{"data":{"person":{"name":"john","address":{"city":"Berlin"},"op":"` + req.OperationName + `"}}}`))
+	}))
+}
+
+func TestGraphQL(t *testing.T) {
+	srv := newGraphQLServer()
+	defer srv.Close()
+	const query = `query GetPerson($id: ID!) { person(id: $id) { name address { city } } }`
+
+	env := goe2e.NewEnv()
+	env.Set("city", "")
+	goe2e.TestRequest(t, &goe2e.TestConfig{
+		Name:             "query GetPerson",
+		SpecOpts:         []goe2e.SpecOption{goe2e.WithUrl(srv.URL), goe2e.WithGraphQL(query, goe2e.H{"id": "1"}, "GetPerson")},
+		ResponseBodyMods: []goe2e.ResponseBodyModifier{env.GraphQLDataToEnv(nil)},
+		PostTestStatements: []goe2e.TestStatement{
+			{Description: "status 200", Statement: goe2e.TestStatusCode(http.StatusOK)},
+			{Description: "no errors", Statement: goe2e.TestGraphQLNoErrors()},
+			{Description: "name", Statement: goe2e.TestGraphQLData("name", "john")},
+			{Description: "operation name", Statement: goe2e.TestGraphQLData("op", "GetPerson")},
+		},
+	})
+	city, _ := env.String("city")
+	assert.Equal(t, "Berlin", city)
+
+	goe2e.TestRequest(t, &goe2e.TestConfig{
+		Name:     "query GetPerson not found",
+		SpecOpts: []goe2e.SpecOption{goe2e.WithUrl(srv.URL), goe2e.WithGraphQL(query, goe2e.H{"id": "2"}, "")},
+		PostTestStatements: []goe2e.TestStatement{
+			{Description: "status 200", Statement: goe2e.TestStatusCode(http.StatusOK)},
+			{Description: "error", Statement: goe2e.TestGraphQLError("not found")},
+			{Description: "errors fail", Statement: func(t *testing.T, rh *goe2e.RequestHandler) {
+				mock := &testing.T{}
+				goe2e.TestGraphQLNoErrors()(mock, rh)
+				assert.True(t, mock.Failed())
+
+				_, err := env.GraphQLDataToEnv(nil)(rh.ResponseBody)
+				assert.EqualError(t, err, "graphql errors: person not found (path: person) [NOT_FOUND]")
+			}},
+		},
+	})
+}
+
+func TestParseGraphQLResponse(t *testing.T) {
+	testCases := []struct {
+		name   string
+		body   string
+		data   goe2e.H
+		errors int
+		err    bool
+	}{
+		{"Data", `{"data":{"a":1}}`, goe2e.H{"a": float64(1)}, 0, false},
+		{"Null data with errors", `{"data":null,"errors":[{"message":"x","locations":[{"line":1,"column":2}]}]}`, nil, 1, false},
+		{"No data", `{"errors":[{"message":"x"}]}`, nil, 1, false},
+		{"Invalid", `<html>`, nil, 0, true},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := goe2e.ParseGraphQLResponse([]byte(tt.body))
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			data, err := resp.DataMap()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.data, data)
+			assert.Len(t, resp.Errors, tt.errors)
+			assert.Equal(t, tt.errors > 0, resp.Err() != nil)
+		})
+	}
+}
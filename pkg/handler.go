@@ -1,9 +1,14 @@
 package goe2e
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"net/http"
+	"slices"
+	"sync"
+	"time"
 )
 
 // RequestHandler handles construction, modification and execution of a http.Request.
@@ -13,6 +18,31 @@ type RequestHandler struct {
 	Client       *http.Client
 	Response     *http.Response
 	ResponseBody []byte
+	// RawResponseBody is the body as received, before decoding its Content-Encoding.
+	// It equals the ResponseBody if the response was not encoded or already decompressed by the http.Transport.
+	RawResponseBody []byte
+	// Events holds the Server-Sent Events read from a stream, see ReadEvents.
+	Events []SSEEvent
+	// RecordCount is the number of records read from a stream, see ReadRecords.
+	RecordCount int
+	// wrappers are applied to the client's transport when running the request, see WithRoundTripper.
+	wrappers []func(http.RoundTripper) http.RoundTripper
+	// maxResponseSize limits the bytes read from a response body, see WithMaxResponseSize.
+	maxResponseSize int64
+	// displayLimit limits the bytes of the response body shown in failure messages, see WithDisplayLimit.
+	displayLimit int
+	// transportOpts configure the handler's own copy of the client's transport, see WithTransportOption.
+	transportOpts []func(*http.Transport) error
+	// transport is built from the transportOpts when sending the first request, and shared with derived handlers.
+	transport *ownTransport
+	// timeout limits each request, see WithTimeout.
+	timeout time.Duration
+	// ctx is the context all requests are sent with, see WithContext.
+	ctx context.Context
+	// checkRedirect replaces the redirect policy of the client, see WithRedirectLimit.
+	checkRedirect func(*http.Request, []*http.Request) error
+	// requestMods are applied to the request before any other modification and before sending it, see WithOAuth2.
+	requestMods []RequestModifier
 }
 
 type RequestHandlerOption func(*RequestHandler) error
@@ -67,15 +97,31 @@ func WithClient(client *http.Client) RequestHandlerOption {
 	}
 }
 
+// WithRoundTripper wraps the transport of the handler's client, e.g. to add authentication or retries.
+// The Client itself is not modified, so it can be shared between handlers. The first wrapper passed is the innermost.
+func WithRoundTripper(wrap func(http.RoundTripper) http.RoundTripper) RequestHandlerOption {
+	return func(rh *RequestHandler) error {
+		rh.wrappers = append(slices.Clip(rh.wrappers), wrap)
+		return nil
+	}
+}
+
+// RoundTripperFunc is an adapter to use an ordinary function as http.RoundTripper.
+type RoundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f RoundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
 // RunRequest will execute the http.Request and write the response to the RequestHandler.ResponseBody.
 func (rh *RequestHandler) RunRequest() error {
 	if rh.spec == nil {
 		return fmt.Errorf("no request specifications initialized before executing")
 	}
-	if rh.Client == nil {
-		rh.Client = &http.Client{}
+	if err := rh.ModifyRequest(); err != nil {
+		return err
 	}
-	resp, err := rh.Client.Do(rh.spec.Request)
+	resp, err := rh.send(rh.spec.Request)
 	if err != nil {
 		return err
 	}
@@ -85,21 +131,216 @@ func (rh *RequestHandler) RunRequest() error {
 		return nil
 	}
 	defer resp.Body.Close()
-	b, err := io.ReadAll(resp.Body)
+	rh.Response = resp
+	// fail early if the server announces a body that is too large
+	if rh.maxResponseSize > 0 && resp.ContentLength > rh.maxResponseSize {
+		return fmt.Errorf("%w: Content-Length of %d bytes exceeds limit of %d bytes", ErrBodyTooLarge, resp.ContentLength, rh.maxResponseSize)
+	}
+	raw, err := io.ReadAll(limitBody(resp.Body, rh.maxResponseSize, 0))
+	if err != nil {
+		return fmt.Errorf("reading response body failed: %w", err)
+	}
+	rh.RawResponseBody = raw
+	rh.ResponseBody = raw
+	// responses without content, like to HEAD requests, may still carry the Content-Encoding
+	if len(raw) == 0 || len(responseEncodings(resp)) == 0 || resp.Uncompressed {
+		return nil
+	}
+	decoded, err := decodeBody(resp, io.NopCloser(bytes.NewReader(raw)))
 	if err != nil {
 		return err
 	}
+	defer decoded.Close()
+	b, err := io.ReadAll(limitBody(decoded, rh.maxResponseSize, 0))
+	if err != nil {
+		return fmt.Errorf("decoding response body failed: %w", err)
+	}
 	rh.ResponseBody = b
+	return nil
+}
+
+// RunStream executes the http.Request like RunRequest, but leaves the response body open for reading it as a stream, e.g. with ReadEvents.
+// An encoded body is decompressed while reading it.
+// The caller is responsible for closing the stream with (RequestHandler).Close.
+func (rh *RequestHandler) RunStream() error {
+	if rh.spec == nil {
+		return fmt.Errorf("no request specifications initialized before executing")
+	}
+	if err := rh.ModifyRequest(); err != nil {
+		return err
+	}
+	resp, err := rh.send(rh.spec.Request)
+	if err != nil {
+		return err
+	}
 	rh.Response = resp
+	body, err := decodeBody(resp, resp.Body)
+	if err != nil {
+		resp.Body.Close()
+		return err
+	}
+	resp.Body = body
 	return nil
 }
 
-// Close closes the response body. "You should never need it, but it is here" - Justin Case.
+// derive creates a handler for sending r, sharing the client and options of the handler, e.g. for a follow-up request.
+func (rh *RequestHandler) derive(r *http.Request) *RequestHandler {
+	spec := *rh.spec
+	spec.Method = r.Method
+	spec.Request = r
+	return &RequestHandler{
+		spec:            &spec,
+		Client:          rh.Client,
+		wrappers:        rh.wrappers,
+		maxResponseSize: rh.maxResponseSize,
+		displayLimit:    rh.displayLimit,
+		transportOpts:   rh.transportOpts,
+		transport:       rh.transport,
+		timeout:         rh.timeout,
+		ctx:             rh.ctx,
+		checkRedirect:   rh.checkRedirect,
+	}
+}
+
+// send executes a request with the handler's client and context, limited by the handler's timeout.
+// The timeout stays active until the response body is closed, except for protocol switches like WebSocket upgrades,
+// where it only covers the handshake.
+func (rh *RequestHandler) send(r *http.Request) (*http.Response, error) {
+	if rh.Client == nil {
+		rh.Client = &http.Client{}
+	}
+	if rh.ctx != nil {
+		r = r.WithContext(rh.ctx)
+	}
+	client, err := rh.httpClient()
+	if err != nil {
+		return nil, err
+	}
+	timeout := rh.requestTimeout()
+	if timeout <= 0 {
+		return client.Do(r)
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	resp, err := client.Do(r.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	// the caller owns an upgraded connection, it is not closed by cancelling the request
+	if resp.StatusCode == http.StatusSwitchingProtocols {
+		cancel()
+		return resp, nil
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelBody releases the timeout of a request when its response body is closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
+
+// httpClient returns the client for sending the request, with the handler's redirect policy and own transport if it has transport options,
+// wrapped by all round trippers of the handler.
+func (rh *RequestHandler) httpClient() (*http.Client, error) {
+	timeout := rh.requestTimeout()
+	if len(rh.wrappers) == 0 && len(rh.transportOpts) == 0 && timeout >= 0 && rh.checkRedirect == nil {
+		return rh.Client, nil
+	}
+	c := *rh.Client
+	// the handler's own timeout is applied by send, a negative one disables the client's as well
+	if timeout < 0 {
+		c.Timeout = 0
+	}
+	if rh.checkRedirect != nil {
+		c.CheckRedirect = rh.checkRedirect
+	}
+	rt := c.Transport
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	if len(rh.transportOpts) > 0 {
+		t, err := rh.transport.get(rt, rh.transportOpts)
+		if err != nil {
+			return nil, err
+		}
+		rt = t
+	}
+	for _, wrap := range rh.wrappers {
+		rt = wrap(rt)
+	}
+	c.Transport = rt
+	return &c, nil
+}
+
+// ownTransport is the handler's own copy of the client's transport, so the client and its transport can still be shared with other handlers.
+type ownTransport struct {
+	mu sync.Mutex
+	t  *http.Transport
+}
+
+// get clones the base transport once and applies the transport options to the clone.
+func (ot *ownTransport) get(base http.RoundTripper, opts []func(*http.Transport) error) (*http.Transport, error) {
+	ot.mu.Lock()
+	defer ot.mu.Unlock()
+	if ot.t != nil {
+		return ot.t, nil
+	}
+	bt, ok := base.(*http.Transport)
+	if !ok {
+		return nil, fmt.Errorf("transport options require the client's transport to be a *http.Transport, got %T", base)
+	}
+	t := bt.Clone()
+	// the clone only sets up HTTP/2 for a custom TLS config if forced, but still offers it if the original did
+	if t.TLSClientConfig != nil && slices.Contains(t.TLSClientConfig.NextProtos, "h2") {
+		t.ForceAttemptHTTP2 = true
+	}
+	for _, opt := range opts {
+		if err := opt(t); err != nil {
+			return nil, fmt.Errorf("configuring transport failed: %w", err)
+		}
+	}
+	ot.t = t
+	return t, nil
+}
+
+// closeIdle closes the idle connections of the transport, if it was built.
+func (ot *ownTransport) closeIdle() {
+	ot.mu.Lock()
+	defer ot.mu.Unlock()
+	if ot.t != nil {
+		ot.t.CloseIdleConnections()
+	}
+}
+
+// WithTransportOption configures the transport used by the handler, like its TLS or proxy settings.
+// The options are applied to a copy of the client's transport, which has to be a *http.Transport, or of the http.DefaultTransport.
+func WithTransportOption(opt func(*http.Transport) error) RequestHandlerOption {
+	return func(rh *RequestHandler) error {
+		// derived handlers share the options, appending must not change those of the others
+		rh.transportOpts = append(slices.Clip(rh.transportOpts), opt)
+		rh.transport = &ownTransport{}
+		return nil
+	}
+}
+
+// Close closes the response body and the idle connections of the handler's own transport, see WithTransportOption.
+// TestRequest and the other test routines close their handlers when they are done.
 func (rh *RequestHandler) Close() error {
-	if rh.Response != nil {
-		return rh.Response.Body.Close()
+	var err error
+	if rh.Response != nil && rh.Response.Body != nil {
+		err = rh.Response.Body.Close()
 	}
-	return nil
+	if rh.transport != nil {
+		rh.transport.closeIdle()
+	}
+	return err
 }
 
 // GetRequest returns the http.Request for inspection during assertions.
@@ -108,11 +349,12 @@ func (rh *RequestHandler) GetRequest() *http.Request {
 	return rh.spec.Request
 }
 
+// ModifyRequest applies the RequestModifiers to the http.Request, after those registered by handler options like WithOAuth2.
 func (rh *RequestHandler) ModifyRequest(respBodyOpts ...RequestModifier) error {
 	if rh.spec.Request == nil {
 		return fmt.Errorf("no http.Request generated before modifying it")
 	}
-	for _, opt := range respBodyOpts {
+	for _, opt := range slices.Concat(rh.requestMods, respBodyOpts) {
 		err := opt(rh.spec.Request)
 		if err != nil {
 			return fmt.Errorf("modifying http.Request failed: %w", err)
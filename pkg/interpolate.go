@@ -0,0 +1,195 @@
+package goe2e
+
+import (
+	"bytes"
+	crand "crypto/rand"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// templateVar matches {{name}} and {{ name }} placeholders.
+var templateVar = regexp.MustCompile(`\{\{\s*([^{}\s]+)\s*\}\}`)
+
+// TemplateGenerators holds the built-in {{$name}} variables, which produce a fresh value on every use.
+// Add your own generators to make them available to all templates.
+var TemplateGenerators = map[string]func() (string, error){
+	"$uuid":      newUUID,
+	"$timestamp": func() (string, error) { return strconv.FormatInt(time.Now().Unix(), 10), nil },
+	"$randomInt": func() (string, error) { return strconv.Itoa(rand.IntN(1001)), nil },
+}
+
+// Interpolate replaces all {{name}} placeholders in s with the values stored under name in env.
+// Names starting with $ are resolved from the TemplateGenerators instead.
+// Returns an error if a placeholder refers to an undefined variable.
+func Interpolate(s string, env H) (string, error) {
+	return interpolate(s, mapEnv(env))
+}
+
+// Interpolate is the Env counterpart of the Interpolate function, looking up the names from the scope it is called on outwards.
+func (e *Env) Interpolate(s string) (string, error) {
+	return interpolate(s, e)
+}
+
+func interpolate(s string, env envStore) (string, error) {
+	var firstErr error
+	out := templateVar.ReplaceAllStringFunc(s, func(match string) string {
+		if firstErr != nil {
+			return match
+		}
+		val, err := resolveTemplateVar(templateVar.FindStringSubmatch(match)[1], env)
+		if err != nil {
+			firstErr = err
+			return match
+		}
+		return fmt.Sprint(val)
+	})
+	if firstErr != nil {
+		return "", firstErr
+	}
+	return out, nil
+}
+
+// WithInterpolation resolves {{name}} placeholders in the request's url and body from env.
+// JSON bodies are interpolated value by value, so the inserted values are properly escaped.
+// A JSON string consisting of a single placeholder, like "{{age}}", is replaced by the value with its type, e.g. a number.
+// Should be passed after the SpecOptions that set the url and body.
+func WithInterpolation(env H) SpecOption {
+	return withInterpolation(mapEnv(env))
+}
+
+// WithInterpolation is the Env counterpart of the WithInterpolation function.
+func (e *Env) WithInterpolation() SpecOption {
+	return withInterpolation(e)
+}
+
+func withInterpolation(store envStore) SpecOption {
+	return func(rs *Spec) error {
+		u, err := interpolate(rs.Url, store)
+		if err != nil {
+			return fmt.Errorf("spec option WithInterpolation failed - url: %s", err.Error())
+		}
+		rs.Url = u
+		if !bytes.Contains(rs.Body, []byte("{{")) {
+			return nil
+		}
+		body, err := interpolateBody(rs.Body, store)
+		if err != nil {
+			return fmt.Errorf("spec option WithInterpolation failed - body: %s", err.Error())
+		}
+		rs.Body = body
+		return nil
+	}
+}
+
+// WithInterpolatedHeaders resolves {{name}} placeholders in all header values of the http.Request from env.
+// Should be passed after the RequestModifiers that set the headers.
+func WithInterpolatedHeaders(env H) RequestModifier {
+	return withInterpolatedHeaders(mapEnv(env))
+}
+
+// WithInterpolatedHeaders is the Env counterpart of the WithInterpolatedHeaders function.
+func (e *Env) WithInterpolatedHeaders() RequestModifier {
+	return withInterpolatedHeaders(e)
+}
+
+func withInterpolatedHeaders(store envStore) RequestModifier {
+	return func(r *http.Request) error {
+		for k, vals := range r.Header {
+			for i, v := range vals {
+				iv, err := interpolate(v, store)
+				if err != nil {
+					return fmt.Errorf("interpolating header %s failed: %w", k, err)
+				}
+				vals[i] = iv
+			}
+		}
+		return nil
+	}
+}
+
+func resolveTemplateVar(name string, env envStore) (any, error) {
+	if strings.HasPrefix(name, "$") {
+		gen, ok := TemplateGenerators[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown template generator {{%s}}", name)
+		}
+		return gen()
+	}
+	val, ok := env.lookup(name)
+	if !ok {
+		return nil, fmt.Errorf("undefined template variable {{%s}}", name)
+	}
+	return val, nil
+}
+
+// interpolateBody interpolates valid JSON bodies value by value and everything else as plain text.
+func interpolateBody(body []byte, env envStore) ([]byte, error) {
+	if !json.Valid(body) {
+		s, err := interpolate(string(body), env)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(s), nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	doc, err := interpolateJSONValue(doc, env)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+func interpolateJSONValue(v any, env envStore) (any, error) {
+	switch t := v.(type) {
+	case string:
+		if m := templateVar.FindStringSubmatch(t); m != nil && m[0] == t {
+			return resolveTemplateVar(m[1], env)
+		}
+		return interpolate(t, env)
+	case H:
+		for k, val := range t {
+			iv, err := interpolateJSONValue(val, env)
+			if err != nil {
+				return nil, err
+			}
+			t[k] = iv
+		}
+	case []any:
+		for i, val := range t {
+			iv, err := interpolateJSONValue(val, env)
+			if err != nil {
+				return nil, err
+			}
+			t[i] = iv
+		}
+	}
+	return v, nil
+}
+
+// newUUID generates a random version 4 UUID.
+func newUUID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := crand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
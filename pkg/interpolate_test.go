@@ -0,0 +1,110 @@
+package goe2e_test
+
+import (
+	"regexp"
+	"strconv"
+	"testing"
+
+	goe2e "github.com/J-Bockhofer/goe2e/pkg"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInterpolate(t *testing.T) {
+	vars := goe2e.H{
+		"host": "localhost:8080",
+		"id":   42,
+	}
+	testCases := []struct {
+		name     string
+		template string
+		expected string
+		err      bool
+	}{
+		{"No placeholder", "http://example.com", "http://example.com", false},
+		{"Single", "http://{{host}}/persons", "http://localhost:8080/persons", false},
+		{"Spaces and number", "/persons/{{ id }}?host={{host}}", "/persons/42?host=localhost:8080", false},
+		{"Undefined", "/persons/{{unknown}}", "", true},
+		{"Unknown generator", "{{$nope}}", "", true},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := goe2e.Interpolate(tt.template, vars)
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestInterpolateGenerators(t *testing.T) {
+	uuid, err := goe2e.Interpolate("{{$uuid}}", nil)
+	assert.NoError(t, err)
+	assert.Regexp(t, regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`), uuid)
+
+	ts, err := goe2e.Interpolate("{{$timestamp}}", nil)
+	assert.NoError(t, err)
+	_, err = strconv.ParseInt(ts, 10, 64)
+	assert.NoError(t, err)
+
+	n, err := goe2e.Interpolate("{{$randomInt}}", nil)
+	assert.NoError(t, err)
+	i, err := strconv.Atoi(n)
+	assert.NoError(t, err)
+	assert.True(t, i >= 0 && i <= 1000)
+}
+
+func TestWithInterpolation(t *testing.T) {
+	vars := goe2e.H{
+		"baseUrl": "http://localhost:8080",
+		"name":    `jo"hn`,
+		"age":     32,
+		"token":   "abc",
+	}
+	testCases := []struct {
+		name         string
+		opts         []goe2e.SpecOption
+		expectedUrl  string
+		expectedBody string
+	}{
+		{"Url", []goe2e.SpecOption{
+			goe2e.WithUrl("{{baseUrl}}/persons"),
+		}, "http://localhost:8080/persons", ""},
+		{"JSON body", []goe2e.SpecOption{
+			goe2e.WithJSON(goe2e.H{"name": "{{name}}", "age": "{{age}}", "tags": []string{"x-{{age}}"}}),
+		}, defaultUrl, `{"age":32,"name":"jo\"hn","tags":["x-32"]}`},
+		{"Raw body", []goe2e.SpecOption{
+			goe2e.WithBody([]byte(`name={{name}}&age={{age}}`)),
+		}, defaultUrl, `name=jo"hn&age=32`},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, err := goe2e.NewSpec(append(tt.opts, goe2e.WithInterpolation(vars))...)
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, tt.expectedUrl, spec.Url)
+			assert.Equal(t, tt.expectedBody, string(spec.Body))
+		})
+	}
+
+	t.Run("Undefined variable", func(t *testing.T) {
+		_, err := goe2e.NewSpec(goe2e.WithJSON(goe2e.H{"name": "{{missing}}"}), goe2e.WithInterpolation(vars))
+		assert.Error(t, err)
+	})
+
+	t.Run("Headers", func(t *testing.T) {
+		spec, err := goe2e.NewSpec()
+		if !assert.NoError(t, err) {
+			return
+		}
+		err = goe2e.WithHeaders(goe2e.D{"Authorization": "Bearer {{token}}"})(spec.Request)
+		assert.NoError(t, err)
+		err = goe2e.WithInterpolatedHeaders(vars)(spec.Request)
+		assert.NoError(t, err)
+		assert.Equal(t, "Bearer abc", spec.Request.Header.Get("Authorization"))
+	})
+}
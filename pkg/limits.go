@@ -0,0 +1,107 @@
+package goe2e
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// DefaultDisplayLimit is the number of bytes of a response body shown in failure messages, see WithDisplayLimit.
+var DefaultDisplayLimit = 1024
+
+// ErrBodyTooLarge is returned when more than the allowed number of bytes is read from a response body.
+var ErrBodyTooLarge = errors.New("response body too large")
+
+// WithMaxResponseSize aborts reading a response body larger than n bytes with ErrBodyTooLarge.
+// It applies to RunRequest as well as to streams read with ReadEvents, ReadRecords and ReadChunks, 0 means no limit.
+func WithMaxResponseSize(n int64) RequestHandlerOption {
+	return func(rh *RequestHandler) error {
+		if n < 0 {
+			return fmt.Errorf("max response size must not be negative, got %d", n)
+		}
+		rh.maxResponseSize = n
+		return nil
+	}
+}
+
+// WithDisplayLimit sets how many bytes of the response body are shown in failure messages, overriding the DefaultDisplayLimit.
+// A negative limit shows the whole body.
+func WithDisplayLimit(n int) RequestHandlerOption {
+	return func(rh *RequestHandler) error {
+		rh.displayLimit = n
+		return nil
+	}
+}
+
+// BodyPreview returns the ResponseBody for failure messages, truncated to the display limit and with all secrets redacted.
+func (rh *RequestHandler) BodyPreview() string {
+	return rh.preview(rh.ResponseBody)
+}
+
+// preview redacts all secrets from b and truncates it to the display limit of the handler.
+// Redacting first keeps a secret cut in half from showing up.
+func (rh *RequestHandler) preview(b []byte) string {
+	limit := rh.displayLimit
+	if limit == 0 {
+		limit = DefaultDisplayLimit
+	}
+	return Preview([]byte(DefaultSecrets.Redact(string(b))), limit)
+}
+
+// Preview returns b as string, cut after limit bytes with a note on how much was left out.
+// The cut never splits a UTF-8 encoded character. A negative limit returns the whole of b.
+func Preview(b []byte, limit int) string {
+	if limit < 0 || len(b) <= limit {
+		return string(b)
+	}
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(b[cut]) {
+		cut--
+	}
+	return fmt.Sprintf("%s... (%d more bytes, %d in total)", b[:cut], len(b)-cut, len(b))
+}
+
+// bodyLimit returns limit or, if it is 0, the max response size of the handler.
+func (rh *RequestHandler) bodyLimit(limit int64) int64 {
+	if limit == 0 {
+		return rh.maxResponseSize
+	}
+	return limit
+}
+
+// limitedBody fails with ErrBodyTooLarge instead of silently truncating the body like io.LimitReader.
+type limitedBody struct {
+	r     io.Reader
+	limit int64
+	read  int64
+}
+
+func (lb *limitedBody) Read(p []byte) (int, error) {
+	if lb.read > lb.limit {
+		return 0, lb.err()
+	}
+	// read at most one byte past the limit to detect a body exceeding it
+	if remaining := lb.limit - lb.read + 1; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	n, err := lb.r.Read(p)
+	lb.read += int64(n)
+	if lb.read > lb.limit {
+		return n - int(lb.read-lb.limit), lb.err()
+	}
+	return n, err
+}
+
+func (lb *limitedBody) err() error {
+	return fmt.Errorf("%w: exceeds limit of %d bytes", ErrBodyTooLarge, lb.limit)
+}
+
+// limitBody wraps r to fail once more than limit bytes were read in total, counting the bytes already read from previous streams.
+// A limit of 0 returns r unchanged.
+func limitBody(r io.Reader, limit int64, read int64) io.Reader {
+	if limit <= 0 {
+		return r
+	}
+	return &limitedBody{r: r, limit: limit, read: read}
+}
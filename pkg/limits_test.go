@@ -0,0 +1,126 @@
+package goe2e_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	goe2e "github.com/J-Bockhofer/goe2e/pkg"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPreview(t *testing.T) {
+	testCases := []struct {
+		name     string
+		body     string
+		limit    int
+		expected string
+	}{
+		{"Short", "hello", 10, "hello"},
+		{"Exact", "hello", 5, "hello"},
+		{"Truncated", "hello world", 5, "hello... (6 more bytes, 11 in total)"},
+		{"Multi-byte character", "aäb", 2, "a... (3 more bytes, 4 in total)"},
+		{"No limit", "hello world", -1, "hello world"},
+		{"Empty", "", 5, ""},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, goe2e.Preview([]byte(tt.body), tt.limit))
+		})
+	}
+}
+
+func TestMaxResponseSize(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/fixed":
+			w.Header().Set("Content-Length", "2000")
+			fmt.Fprint(w, strings.Repeat("a", 2000))
+		case "/chunked":
+			for i := 0; i < 20; i++ {
+				fmt.Fprint(w, strings.Repeat("b", 100))
+				w.(http.Flusher).Flush()
+			}
+		case "/events":
+			w.Header().Set("Content-Type", goe2e.ContentHeaderEventStream)
+			for i := 0; i < 100; i++ {
+				fmt.Fprintf(w, "data: %d\n\n", i)
+			}
+		}
+	}))
+	defer srv.Close()
+
+	testCases := []struct {
+		name    string
+		path    string
+		maxSize int64
+		err     string
+	}{
+		{"Below limit", "/fixed", 2000, ""},
+		{"Content-Length over limit", "/fixed", 1000, "response body too large: Content-Length of 2000 bytes exceeds limit of 1000 bytes"},
+		{"Chunked below limit", "/chunked", 2000, ""},
+		{"Chunked over limit", "/chunked", 1000, "reading response body failed: response body too large: exceeds limit of 1000 bytes"},
+		{"No limit", "/chunked", 0, ""},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			rh, err := goe2e.NewRequestHandler(
+				goe2e.WithSpecOpts(goe2e.WithUrl(srv.URL+tt.path)),
+				goe2e.WithMaxResponseSize(tt.maxSize),
+			)
+			if !assert.NoError(t, err) {
+				return
+			}
+			err = rh.RunRequest()
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				assert.ErrorIs(t, err, goe2e.ErrBodyTooLarge)
+				assert.Equal(t, http.StatusOK, rh.Response.StatusCode)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Len(t, rh.ResponseBody, 2000)
+		})
+	}
+
+	t.Run("Event stream", func(t *testing.T) {
+		_, err := goe2e.RunConfig(&goe2e.TestConfig{
+			Name:        "GET /events",
+			SpecOpts:    []goe2e.SpecOption{goe2e.WithUrl(srv.URL + "/events")},
+			HandlerOpts: []goe2e.RequestHandlerOption{goe2e.WithMaxResponseSize(100)},
+			SSE:         &goe2e.SSEConfig{Reconnects: 3},
+		})
+		assert.ErrorIs(t, err, goe2e.ErrBodyTooLarge)
+	})
+
+	t.Run("Negative", func(t *testing.T) {
+		_, err := goe2e.NewRequestHandler(goe2e.WithMaxResponseSize(-1))
+		assert.Error(t, err)
+	})
+}
+
+func TestBodyPreview(t *testing.T) {
+	goe2e.DefaultSecrets.Add("preview-secret")
+	body := []byte(`{"token":"preview-secret","data":"` + strings.Repeat("x", 5000) + `"}`)
+
+	rh := &goe2e.RequestHandler{ResponseBody: body}
+	preview := rh.BodyPreview()
+	assert.True(t, strings.HasPrefix(preview, `{"token":"[REDACTED]"`))
+	assert.Contains(t, preview, "more bytes")
+	assert.Less(t, len(preview), goe2e.DefaultDisplayLimit+100)
+
+	rh, err := goe2e.NewRequestHandler(goe2e.WithDisplayLimit(20))
+	if assert.NoError(t, err) {
+		rh.ResponseBody = body
+		assert.True(t, strings.HasPrefix(rh.BodyPreview(), `{"token":"[REDACTED]... (`))
+	}
+
+	rh, err = goe2e.NewRequestHandler(goe2e.WithDisplayLimit(-1))
+	if assert.NoError(t, err) {
+		rh.ResponseBody = body
+		assert.NotContains(t, rh.BodyPreview(), "more bytes")
+	}
+}
@@ -0,0 +1,201 @@
+package goe2e
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OAuth2 grant types supported by OAuth2Config.
+const (
+	GrantClientCredentials = "client_credentials"
+	GrantPassword          = "password"
+	GrantRefreshToken      = "refresh_token"
+)
+
+// OAuth2Config obtains access tokens from an OAuth2 token endpoint and caches them until they expire.
+// Obtained refresh tokens are used to renew expired access tokens, falling back to the configured grant if refreshing fails.
+type OAuth2Config struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	// GrantType is one of the Grant constants, GrantClientCredentials by default.
+	GrantType string
+	// Username and Password are sent with GrantPassword.
+	Username string
+	Password string
+	// RefreshToken is required for GrantRefreshToken and updated whenever the token endpoint issues a new one.
+	RefreshToken string
+	// Params are additional form parameters for the token request, like an audience.
+	Params D
+	// AuthInBody sends the client credentials as form parameters instead of a Basic Authorization header.
+	AuthInBody bool
+	// ExpiryDelta renews tokens this long before they expire, 10 seconds by default.
+	ExpiryDelta time.Duration
+	// Client sends the token requests, a default client if nil.
+	Client *http.Client
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+// oauth2Token is the token endpoint's response, see RFC 6749 section 5.
+type oauth2Token struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	ExpiresIn        int64  `json:"expires_in"`
+	RefreshToken     string `json:"refresh_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// WithOAuth2 attaches an access token from cfg to the handler's request as bearer token, so it shows in GetRequest and Curl.
+// The token is requested when the request is modified or sent, not when creating the handler.
+// Like WithLogin, expired tokens are renewed before sending and a 401 Unauthorized response renews the token and retries the request once.
+func WithOAuth2(cfg *OAuth2Config) RequestHandlerOption {
+	return func(rh *RequestHandler) error {
+		rh.requestMods = append(slices.Clip(rh.requestMods), cfg.RequestModifier())
+		return WithRoundTripper(func(next http.RoundTripper) http.RoundTripper {
+			return &tokenTransport{
+				next:    next,
+				token:   cfg.Token,
+				refresh: cfg.Refresh,
+				apply:   ApplyBearer(),
+			}
+		})(rh)
+	}
+}
+
+// RequestModifier attaches the access token to the request as bearer token, requesting one if there is none yet.
+// The token is registered with the DefaultSecrets.
+func (c *OAuth2Config) RequestModifier() RequestModifier {
+	return func(r *http.Request) error {
+		token, err := c.Token()
+		if err != nil {
+			return err
+		}
+		DefaultSecrets.Add(token)
+		ApplyBearer()(r, token)
+		return nil
+	}
+}
+
+// Token returns a valid access token, requesting a new one if there is none or it is about to expire.
+func (c *OAuth2Config) Token() (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != "" && (c.expiry.IsZero() || time.Now().Add(c.expiryDelta()).Before(c.expiry)) {
+		return c.token, nil
+	}
+	return c.renew()
+}
+
+// Refresh discards the cached access token and obtains a new one.
+func (c *OAuth2Config) Refresh() (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.renew()
+}
+
+func (c *OAuth2Config) renew() (string, error) {
+	c.token = ""
+	grant := c.GrantType
+	if grant == "" {
+		grant = GrantClientCredentials
+	}
+	if c.RefreshToken != "" && grant != GrantRefreshToken {
+		if err := c.requestToken(GrantRefreshToken); err == nil {
+			return c.token, nil
+		}
+	}
+	if err := c.requestToken(grant); err != nil {
+		return "", err
+	}
+	return c.token, nil
+}
+
+func (c *OAuth2Config) requestToken(grant string) error {
+	form := url.Values{"grant_type": {grant}}
+	switch grant {
+	case GrantPassword:
+		form.Set("username", c.Username)
+		form.Set("password", c.Password)
+		DefaultSecrets.Add(c.Password)
+	case GrantRefreshToken:
+		if c.RefreshToken == "" {
+			return fmt.Errorf("oauth2 token request failed: no refresh token")
+		}
+		form.Set("refresh_token", c.RefreshToken)
+	case GrantClientCredentials:
+	default:
+		return fmt.Errorf("oauth2 token request failed: unsupported grant type %s", grant)
+	}
+	if len(c.Scopes) > 0 {
+		form.Set("scope", strings.Join(c.Scopes, " "))
+	}
+	for k, v := range c.Params {
+		form.Set(k, v)
+	}
+	if c.AuthInBody {
+		form.Set("client_id", c.ClientID)
+		form.Set("client_secret", c.ClientSecret)
+	}
+	DefaultSecrets.Add(c.ClientSecret)
+	req, err := http.NewRequest(http.MethodPost, c.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("oauth2 token request failed: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", ContentHeaderJSON)
+	if !c.AuthInBody {
+		req.SetBasicAuth(url.QueryEscape(c.ClientID), url.QueryEscape(c.ClientSecret))
+	}
+	client := c.Client
+	if client == nil {
+		client = &http.Client{}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("oauth2 token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("oauth2 token request failed: %w", err)
+	}
+	var tok oauth2Token
+	if err := json.Unmarshal(b, &tok); err != nil {
+		return fmt.Errorf("oauth2 token request failed with status %d - json.Unmarshal: %s", resp.StatusCode, err.Error())
+	}
+	if tok.Error != "" {
+		return fmt.Errorf("oauth2 token request failed: %s %s", tok.Error, tok.ErrorDescription)
+	}
+	if resp.StatusCode != http.StatusOK || tok.AccessToken == "" {
+		return fmt.Errorf("oauth2 token request failed with status %d", resp.StatusCode)
+	}
+	DefaultSecrets.Add(tok.AccessToken, tok.RefreshToken)
+	c.token = tok.AccessToken
+	c.expiry = time.Time{}
+	if tok.ExpiresIn > 0 {
+		c.expiry = time.Now().Add(time.Duration(tok.ExpiresIn) * time.Second)
+	}
+	if tok.RefreshToken != "" {
+		c.RefreshToken = tok.RefreshToken
+	}
+	return nil
+}
+
+func (c *OAuth2Config) expiryDelta() time.Duration {
+	if c.ExpiryDelta == 0 {
+		return 10 * time.Second
+	}
+	return c.ExpiryDelta
+}
@@ -0,0 +1,163 @@
+package goe2e_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	goe2e "github.com/J-Bockhofer/goe2e/pkg"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// tokenServer is a stub OAuth2 token endpoint that also serves a protected /api route.
+type tokenServer struct {
+	expiresIn int
+	issued    int
+	grants    []string
+	valid     string
+}
+
+func (s *tokenServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/token":
+		r.ParseForm()
+		user, pass, _ := r.BasicAuth()
+		if r.PostForm.Get("client_id") != "" {
+			user, pass = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+		}
+		grant := r.PostForm.Get("grant_type")
+		s.grants = append(s.grants, grant)
+		if user != "client" || pass != "s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(goe2e.H{"error": "invalid_client"})
+			return
+		}
+		if grant == goe2e.GrantPassword && r.PostForm.Get("password") != "hunter22" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(goe2e.H{"error": "invalid_grant"})
+			return
+		}
+		s.issued++
+		s.valid = fmt.Sprintf("access-%d", s.issued)
+		json.NewEncoder(w).Encode(goe2e.H{
+			"access_token":  s.valid,
+			"token_type":    "Bearer",
+			"expires_in":    s.expiresIn,
+			"refresh_token": fmt.Sprintf("refresh-%d", s.issued),
+		})
+	case "/api":
+		if r.Header.Get("Authorization") != "Bearer "+s.valid {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte("ok"))
+	}
+}
+
+func TestOAuth2Grants(t *testing.T) {
+	testCases := []struct {
+		name  string
+		cfg   *goe2e.OAuth2Config
+		grant string
+		err   bool
+	}{
+		{"Client credentials", &goe2e.OAuth2Config{ClientID: "client", ClientSecret: "s3cret", Scopes: []string{"read"}}, goe2e.GrantClientCredentials, false},
+		{"Credentials in body", &goe2e.OAuth2Config{ClientID: "client", ClientSecret: "s3cret", AuthInBody: true}, goe2e.GrantClientCredentials, false},
+		{"Password", &goe2e.OAuth2Config{ClientID: "client", ClientSecret: "s3cret", GrantType: goe2e.GrantPassword, Username: "john", Password: "hunter22"}, goe2e.GrantPassword, false},
+		{"Refresh token", &goe2e.OAuth2Config{ClientID: "client", ClientSecret: "s3cret", GrantType: goe2e.GrantRefreshToken, RefreshToken: "refresh-0"}, goe2e.GrantRefreshToken, false},
+		{"Wrong password", &goe2e.OAuth2Config{ClientID: "client", ClientSecret: "s3cret", GrantType: goe2e.GrantPassword, Password: "wrong"}, goe2e.GrantPassword, true},
+		{"Wrong client", &goe2e.OAuth2Config{ClientID: "client", ClientSecret: "wrong"}, goe2e.GrantClientCredentials, true},
+		{"Unsupported grant", &goe2e.OAuth2Config{GrantType: "implicit"}, "", true},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := &tokenServer{expiresIn: 3600}
+			ts := httptest.NewServer(srv)
+			defer ts.Close()
+			cfg := tt.cfg
+			cfg.TokenURL = ts.URL + "/token"
+			token, err := cfg.Token()
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, "access-1", token)
+			assert.Equal(t, []string{tt.grant}, srv.grants)
+			assert.Equal(t, "refresh-1", cfg.RefreshToken)
+		})
+	}
+}
+
+func TestOAuth2Caching(t *testing.T) {
+	t.Run("Cached until expiry", func(t *testing.T) {
+		srv := &tokenServer{expiresIn: 3600}
+		ts := httptest.NewServer(srv)
+		defer ts.Close()
+		cfg := &goe2e.OAuth2Config{TokenURL: ts.URL + "/token", ClientID: "client", ClientSecret: "s3cret"}
+		for i := 0; i < 3; i++ {
+			token, err := cfg.Token()
+			assert.NoError(t, err)
+			assert.Equal(t, "access-1", token)
+		}
+		assert.Equal(t, 1, srv.issued)
+	})
+	t.Run("Expired tokens are refreshed", func(t *testing.T) {
+		// expiring within the expiry delta forces a renewal on every call
+		srv := &tokenServer{expiresIn: 5}
+		ts := httptest.NewServer(srv)
+		defer ts.Close()
+		cfg := &goe2e.OAuth2Config{TokenURL: ts.URL + "/token", ClientID: "client", ClientSecret: "s3cret"}
+		cfg.Token()
+		token, err := cfg.Token()
+		assert.NoError(t, err)
+		assert.Equal(t, "access-2", token)
+		assert.Equal(t, []string{goe2e.GrantClientCredentials, goe2e.GrantRefreshToken}, srv.grants)
+	})
+}
+
+func TestWithOAuth2(t *testing.T) {
+	srv := &tokenServer{expiresIn: 3600}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+	cfg := &goe2e.OAuth2Config{TokenURL: ts.URL + "/token", ClientID: "client", ClientSecret: "s3cret"}
+	tc := &goe2e.TestConfig{
+		Name:        "GET /api",
+		SpecOpts:    []goe2e.SpecOption{goe2e.WithUrl(ts.URL + "/api")},
+		HandlerOpts: []goe2e.RequestHandlerOption{goe2e.WithOAuth2(cfg)},
+		PreTestStatements: []goe2e.TestStatement{
+			{Description: "token attached", Statement: func(t *testing.T, rh *goe2e.RequestHandler) {
+				auth := rh.GetRequest().Header.Get("Authorization")
+				assert.Regexp(t, `^Bearer access-\d$`, auth)
+				token := strings.TrimPrefix(auth, "Bearer ")
+				assert.Contains(t, rh.Curl(), "-H 'Authorization: ")
+				assert.NotContains(t, rh.Curl(), token)
+				assert.NotContains(t, goe2e.DefaultSecrets.Redact("token "+token), token)
+			}},
+		},
+		PostTestStatements: []goe2e.TestStatement{
+			{Description: "status 200", Statement: goe2e.TestStatusCode(http.StatusOK)},
+		},
+	}
+	goe2e.TestRequest(t, tc)
+	assert.Equal(t, 1, srv.issued)
+
+	// a token revoked by the server is renewed transparently
+	srv.valid = "revoked"
+	goe2e.TestRequest(t, tc)
+	assert.Equal(t, 2, srv.issued)
+
+	t.Run("No token request before sending", func(t *testing.T) {
+		unreachable := &goe2e.OAuth2Config{TokenURL: "http://127.0.0.1:1/token", ClientID: "client"}
+		rh, err := goe2e.NewRequestHandler(goe2e.WithSpecOpts(goe2e.WithUrl(ts.URL+"/api")), goe2e.WithOAuth2(unreachable))
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Empty(t, rh.GetRequest().Header.Get("Authorization"))
+		assert.ErrorContains(t, rh.RunRequest(), "oauth2 token request failed")
+	})
+}
@@ -3,6 +3,7 @@ package goe2e
 import (
 	"encoding/json"
 	"fmt"
+	"html"
 	"strings"
 )
 
@@ -131,3 +132,82 @@ func JoinAsRoute(base, route string) string {
 	}
 	return base + route
 }
+
+// htmlTagAttrs returns the attributes of all occurrences of the given tag in an HTML document.
+// Attribute names are lower-cased and values unescaped. It is no full HTML parser, but sufficient for finding meta and input tags.
+func htmlTagAttrs(doc []byte, tag string) []D {
+	var tags []D
+	s := string(doc)
+	// only ASCII is lowered, so positions in lower and s match
+	lowerBytes := []byte(s)
+	for i, c := range lowerBytes {
+		if 'A' <= c && c <= 'Z' {
+			lowerBytes[i] = c + 'a' - 'A'
+		}
+	}
+	lower := string(lowerBytes)
+	open := "<" + strings.ToLower(tag)
+	for i := strings.Index(lower, open); i >= 0; {
+		pos := i + len(open)
+		// make sure the tag name ends here, e.g. <input and not <inputs
+		if pos < len(s) && !strings.ContainsRune(" \t\r\n/>", rune(s[pos])) {
+			next := strings.Index(lower[pos:], open)
+			if next < 0 {
+				break
+			}
+			i = pos + next
+			continue
+		}
+		attrs, end := parseHTMLAttrs(s, pos)
+		tags = append(tags, attrs)
+		next := strings.Index(lower[end:], open)
+		if next < 0 {
+			break
+		}
+		i = end + next
+	}
+	return tags
+}
+
+// parseHTMLAttrs reads attributes starting at pos until the end of the tag and returns them with the position after the tag.
+func parseHTMLAttrs(s string, pos int) (D, int) {
+	attrs := D{}
+	// a slash separates attributes like whitespace, e.g. in <input/>
+	isSpace := func(c byte) bool { return c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '/' }
+	for pos < len(s) {
+		for pos < len(s) && isSpace(s[pos]) {
+			pos++
+		}
+		if pos >= len(s) || s[pos] == '>' {
+			return attrs, min(pos+1, len(s))
+		}
+		start := pos
+		for pos < len(s) && !isSpace(s[pos]) && s[pos] != '=' && s[pos] != '>' {
+			pos++
+		}
+		name := strings.ToLower(s[start:pos])
+		if pos >= len(s) || s[pos] != '=' {
+			attrs[name] = ""
+			continue
+		}
+		pos++
+		var val string
+		if pos < len(s) && (s[pos] == '"' || s[pos] == '\'') {
+			quote := s[pos]
+			end := strings.IndexByte(s[pos+1:], quote)
+			if end < 0 {
+				return attrs, len(s)
+			}
+			val = s[pos+1 : pos+1+end]
+			pos += end + 2
+		} else {
+			start := pos
+			for pos < len(s) && !strings.ContainsRune(" \t\r\n>", rune(s[pos])) {
+				pos++
+			}
+			val = s[start:pos]
+		}
+		attrs[name] = html.UnescapeString(val)
+	}
+	return attrs, len(s)
+}
@@ -0,0 +1,164 @@
+package goe2e
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProfileEnvVar is the OS environment variable selecting the active profile.
+const ProfileEnvVar = "GOE2E_PROFILE"
+
+// TagDestructive marks a TestConfig that changes state on the server. Read-only profiles refuse to run it.
+const TagDestructive = "destructive"
+
+// Keys under which a Profile stores its settings in the Env returned by (*Profile).Env.
+const (
+	ProfileKeyBaseURL  = "baseUrl"
+	ProfileKeyUsername = "username"
+	ProfileKeyPassword = "password"
+	ProfileKeyToken    = "token"
+	ProfileKeyAPIKey   = "apiKey"
+)
+
+// Profile describes one environment, like local, staging or prod, the same suite can run against.
+type Profile struct {
+	Name     string          `json:"-" yaml:"-"`
+	BaseURL  string          `json:"baseUrl" yaml:"baseUrl"`
+	Auth     ProfileAuth     `json:"auth" yaml:"auth"`
+	Features map[string]bool `json:"features" yaml:"features"`
+	// AllowedTags restricts the TestConfigs run on this profile to those whose tags are all in the list. Empty allows all tags.
+	AllowedTags []string `json:"allowedTags" yaml:"allowedTags"`
+	// ReadOnly profiles refuse TestConfigs tagged with TagDestructive.
+	ReadOnly bool `json:"readOnly" yaml:"readOnly"`
+	// Vars holds additional values for the profile's Env.
+	Vars H `json:"vars" yaml:"vars"`
+}
+
+// ProfileAuth holds the credentials of a Profile.
+type ProfileAuth struct {
+	Username string `json:"username" yaml:"username"`
+	Password string `json:"password" yaml:"password"`
+	Token    string `json:"token" yaml:"token"`
+	APIKey   string `json:"apiKey" yaml:"apiKey"`
+}
+
+// ProfileSet holds all profiles defined in a profile file.
+type ProfileSet struct {
+	// Default is the name of the profile used when ProfileEnvVar is not set.
+	Default  string              `json:"default" yaml:"default"`
+	Profiles map[string]*Profile `json:"profiles" yaml:"profiles"`
+}
+
+// LoadProfiles reads a JSON or YAML file defining named profiles, chosen by the file extension.
+func LoadProfiles(path string) (*ProfileSet, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("loading profiles failed: %w", err)
+	}
+	ps := &ProfileSet{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(b, ps)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(b, ps)
+	default:
+		err = fmt.Errorf("unsupported file extension %s", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("loading profiles from %s failed: %w", path, err)
+	}
+	for name, p := range ps.Profiles {
+		if p == nil {
+			p = &Profile{}
+			ps.Profiles[name] = p
+		}
+		p.Name = name
+	}
+	return ps, nil
+}
+
+// Get returns the profile with the given name.
+func (ps *ProfileSet) Get(name string) (*Profile, error) {
+	p, ok := ps.Profiles[name]
+	if !ok {
+		names := make([]string, 0, len(ps.Profiles))
+		for n := range ps.Profiles {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("profile %s not found, available profiles: %s", name, strings.Join(names, ", "))
+	}
+	return p, nil
+}
+
+// Active returns the profile named by the GOE2E_PROFILE environment variable, falling back to the default profile.
+func (ps *ProfileSet) Active() (*Profile, error) {
+	name := os.Getenv(ProfileEnvVar)
+	if name == "" {
+		name = ps.Default
+	}
+	if name == "" {
+		return nil, fmt.Errorf("no profile selected: set %s or a default profile", ProfileEnvVar)
+	}
+	return ps.Get(name)
+}
+
+// Env creates a new Env holding the profile's Vars, its base url and credentials.
+// The base url is stored under ProfileKeyBaseURL for use with the WithBaseURLFromEnv method.
+// The password, token and api key are stored as secrets.
+func (p *Profile) Env() *Env {
+	env := NewEnv()
+	env.name = "profile:" + p.Name
+	for k, v := range p.Vars {
+		env.set(k, v, env.name)
+	}
+	settings := []struct {
+		key, val string
+		secret   bool
+	}{
+		{ProfileKeyBaseURL, p.BaseURL, false},
+		{ProfileKeyUsername, p.Auth.Username, false},
+		{ProfileKeyPassword, p.Auth.Password, true},
+		{ProfileKeyToken, p.Auth.Token, true},
+		{ProfileKeyAPIKey, p.Auth.APIKey, true},
+	}
+	for _, s := range settings {
+		switch {
+		case s.val == "":
+			continue
+		case s.secret:
+			env.SetSecret(s.key, s.val)
+		default:
+			env.set(s.key, s.val, env.name)
+		}
+	}
+	return env
+}
+
+// Feature reports whether the feature flag name is enabled for the profile.
+func (p *Profile) Feature(name string) bool {
+	return p.Features[name]
+}
+
+// Allows checks if a TestConfig with the given tags may run on the profile.
+func (p *Profile) Allows(tags []string) error {
+	if p.ReadOnly && slices.Contains(tags, TagDestructive) {
+		return fmt.Errorf("profile %s is read-only and refuses tests tagged %s", p.Name, TagDestructive)
+	}
+	if len(p.AllowedTags) == 0 {
+		return nil
+	}
+	for _, tag := range tags {
+		if !slices.Contains(p.AllowedTags, tag) {
+			return fmt.Errorf("profile %s does not allow tests tagged %s", p.Name, tag)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package goe2e_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	goe2e "github.com/J-Bockhofer/goe2e/pkg"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const profilesYAML = `
+default: local
+profiles:
+  local:
+    baseUrl: http://localhost:8080
+    auth:
+      username: admin
+      password: admin
+    features:
+      newCheckout: true
+    vars:
+      tenant: dev
+  prod:
+    baseUrl: https://api.example.com
+    readOnly: true
+    allowedTags: [smoke, destructive]
+`
+
+func writeProfiles(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "profiles.yaml")
+	if err := os.WriteFile(path, []byte(profilesYAML), 0o600); err != nil {
+		t.Fatalf("could not write profiles: %s", err.Error())
+	}
+	return path
+}
+
+func TestLoadProfiles(t *testing.T) {
+	ps, err := goe2e.LoadProfiles(writeProfiles(t))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	t.Run("Default", func(t *testing.T) {
+		t.Setenv(goe2e.ProfileEnvVar, "")
+		p, err := ps.Active()
+		assert.NoError(t, err)
+		assert.Equal(t, "local", p.Name)
+		assert.True(t, p.Feature("newCheckout"))
+		assert.False(t, p.Feature("unknown"))
+	})
+	t.Run("Selected by env var", func(t *testing.T) {
+		t.Setenv(goe2e.ProfileEnvVar, "prod")
+		p, err := ps.Active()
+		assert.NoError(t, err)
+		assert.Equal(t, "prod", p.Name)
+		assert.True(t, p.ReadOnly)
+	})
+	t.Run("Unknown", func(t *testing.T) {
+		t.Setenv(goe2e.ProfileEnvVar, "staging")
+		_, err := ps.Active()
+		assert.Error(t, err)
+	})
+	t.Run("Env", func(t *testing.T) {
+		p, _ := ps.Get("local")
+		env := p.Env()
+		spec, err := goe2e.NewSpec(env.WithBaseURLFromEnv(goe2e.ProfileKeyBaseURL, "persons"))
+		assert.NoError(t, err)
+		assert.Equal(t, "http://localhost:8080/persons", spec.Url)
+		user, _ := env.String(goe2e.ProfileKeyUsername)
+		assert.Equal(t, "admin", user)
+		tenant, _ := env.String("tenant")
+		assert.Equal(t, "dev", tenant)
+		assert.False(t, env.Has(goe2e.ProfileKeyToken))
+	})
+}
+
+func TestProfileAllows(t *testing.T) {
+	readOnly := &goe2e.Profile{Name: "prod", ReadOnly: true, AllowedTags: []string{"smoke", goe2e.TagDestructive}}
+	open := &goe2e.Profile{Name: "local"}
+	testCases := []struct {
+		name    string
+		profile *goe2e.Profile
+		tags    []string
+		allowed bool
+	}{
+		{"Untagged", readOnly, nil, true},
+		{"Allowed tag", readOnly, []string{"smoke"}, true},
+		{"Destructive on read-only", readOnly, []string{goe2e.TagDestructive}, false},
+		{"Tag not allowed", readOnly, []string{"slow"}, false},
+		{"Destructive on open profile", open, []string{goe2e.TagDestructive}, true},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.profile.Allows(tt.tags)
+			assert.Equal(t, tt.allowed, err == nil)
+		})
+	}
+}
+
+func TestRequestProfileGuard(t *testing.T) {
+	hits := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+	}))
+	defer srv.Close()
+
+	tc := &goe2e.TestConfig{
+		Name:    "DELETE /persons",
+		Tags:    []string{goe2e.TagDestructive},
+		Profile: &goe2e.Profile{Name: "prod", ReadOnly: true},
+		SpecOpts: []goe2e.SpecOption{
+			goe2e.WithMethod(http.MethodDelete),
+			goe2e.WithUrl(srv.URL + "/persons"),
+		},
+	}
+	var inner *testing.T
+	t.Run("guarded", func(t *testing.T) {
+		inner = t
+		goe2e.TestRequest(t, tc)
+	})
+	assert.True(t, inner.Skipped())
+	assert.Equal(t, 0, hits)
+
+	_, err := goe2e.RunConfig(tc)
+	assert.ErrorContains(t, err, "profile prod is read-only")
+	assert.Equal(t, 0, hits)
+
+	tc.Profile = &goe2e.Profile{Name: "local"}
+	goe2e.TestRequest(t, tc)
+	assert.Equal(t, 1, hits)
+
+	tc.RequestMods = []goe2e.RequestModifier{goe2e.WithBearerToken("run-config-token-42")}
+	_, err = goe2e.RunConfig(tc)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, hits)
+	assert.NotContains(t, goe2e.DefaultSecrets.Redact("Authorization: Bearer run-config-token-42"), "run-config-token-42")
+}
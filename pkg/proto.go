@@ -0,0 +1,226 @@
+package goe2e
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	ContentHeaderProtobuf string = "application/x-protobuf"
+)
+
+// ProtoMessage is satisfied by the pointer to a generated protobuf message type M.
+// It allows passing the message type itself as type parameter, like DecodeProtoResponse[pb.Person](rh).
+type ProtoMessage[M any] interface {
+	*M
+	proto.Message
+}
+
+// WithProto encodes the message in the protobuf binary format and sets it as the request body.
+// Also sets the matching Content-Type.
+func WithProto(msg proto.Message) SpecOption {
+	return func(rs *Spec) error {
+		b, err := proto.Marshal(msg)
+		if err != nil {
+			return fmt.Errorf("spec option WithProto failed - proto.Marshal: %s", err.Error())
+		}
+		rs.Body = b
+		rs.Header.Set("Content-Type", ContentHeaderProtobuf)
+		return nil
+	}
+}
+
+// DecodeProtoResponse decodes the protobuf encoded ResponseBody into a new message of type M.
+func DecodeProtoResponse[M any, PM ProtoMessage[M]](rh *RequestHandler) (PM, error) {
+	msg := PM(new(M))
+	if err := proto.Unmarshal(rh.ResponseBody, msg); err != nil {
+		return nil, fmt.Errorf("decoding protobuf response failed - proto.Unmarshal: %w", err)
+	}
+	return msg, nil
+}
+
+// TestProtoField asserts a single field of the protobuf response decoded as message type M.
+// The path consists of field names separated by dots, where list elements and map entries are addressed with [index] and [key],
+// e.g. "items[0].sku" or "labels[env]". Enum values are compared by name and messages with proto.Equal.
+// On failure the message is shown in its JSON form.
+func TestProtoField[M any, PM ProtoMessage[M]](path string, expected any) func(*testing.T, *RequestHandler) {
+	return func(t *testing.T, rh *RequestHandler) {
+		msg, err := DecodeProtoResponse[M, PM](rh)
+		if err != nil {
+			assert.Fail(t, err.Error())
+			return
+		}
+		actual, err := protoField(msg.ProtoReflect(), path)
+		if err != nil {
+			assert.Fail(t, err.Error(), "message: %s", rh.preview([]byte(ProtoJSON(msg))))
+			return
+		}
+		if expectedMsg, ok := expected.(proto.Message); ok {
+			actualMsg, _ := actual.(proto.Message)
+			if actualMsg == nil || !proto.Equal(expectedMsg, actualMsg) {
+				assert.Fail(t, fmt.Sprintf("field %s differs", path), "expected: %s\nactual  : %s\nmessage: %s",
+					ProtoJSON(expectedMsg), ProtoJSON(actualMsg), rh.preview([]byte(ProtoJSON(msg))))
+			}
+			return
+		}
+		assert.EqualValues(t, expected, actual, "field %s of message: %s", path, rh.preview([]byte(ProtoJSON(msg))))
+	}
+}
+
+// TestProtoEqual asserts that the protobuf response equals the expected message and shows a diff of their JSON forms otherwise.
+func TestProtoEqual(expected proto.Message) func(*testing.T, *RequestHandler) {
+	return func(t *testing.T, rh *RequestHandler) {
+		actual := expected.ProtoReflect().New().Interface()
+		if err := proto.Unmarshal(rh.ResponseBody, actual); err != nil {
+			assert.Fail(t, fmt.Sprintf("decoding protobuf response failed - proto.Unmarshal: %s", err.Error()))
+			return
+		}
+		if !proto.Equal(expected, actual) {
+			assert.Equal(t, ProtoJSON(expected), ProtoJSON(actual))
+		}
+	}
+}
+
+// ProtoJSON renders a protobuf message in its indented JSON form, e.g. for log and failure output.
+func ProtoJSON(msg proto.Message) string {
+	if msg == nil {
+		return "null"
+	}
+	b, err := protojson.Marshal(msg)
+	if err != nil {
+		return fmt.Sprintf("<%s: %s>", msg.ProtoReflect().Descriptor().FullName(), err.Error())
+	}
+	// protojson randomizes its whitespace, so reformat for a stable output
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, b, "", "  "); err != nil {
+		return string(b)
+	}
+	return buf.String()
+}
+
+// protoField resolves a field path in the message, converting the value for comparison.
+func protoField(msg protoreflect.Message, path string) (any, error) {
+	cur := protoreflect.ValueOfMessage(msg)
+	var fd protoreflect.FieldDescriptor
+	collection := false
+	for _, seg := range strings.Split(path, ".") {
+		if fd != nil && (collection || fd.Kind() != protoreflect.MessageKind && fd.Kind() != protoreflect.GroupKind) {
+			return nil, fmt.Errorf("field path %s: %s is not a message", path, fd.Name())
+		}
+		name, index, hasIndex := strings.Cut(seg, "[")
+		m := cur.Message()
+		fields := m.Descriptor().Fields()
+		fd = fields.ByName(protoreflect.Name(name))
+		if fd == nil {
+			fd = fields.ByJSONName(name)
+		}
+		if fd == nil {
+			return nil, fmt.Errorf("field path %s: %s has no field %s", path, m.Descriptor().FullName(), name)
+		}
+		cur = m.Get(fd)
+		collection = fd.IsList() || fd.IsMap()
+		if !hasIndex {
+			continue
+		}
+		index = strings.TrimSuffix(index, "]")
+		switch {
+		case fd.IsList():
+			i, err := strconv.Atoi(index)
+			if err != nil || i < 0 || i >= cur.List().Len() {
+				return nil, fmt.Errorf("field path %s: index %s out of range for %s with %d elements", path, index, name, cur.List().Len())
+			}
+			cur = cur.List().Get(i)
+		case fd.IsMap():
+			key, err := protoMapKey(fd.MapKey(), index)
+			if err != nil {
+				return nil, fmt.Errorf("field path %s: %w", path, err)
+			}
+			if !cur.Map().Has(key) {
+				return nil, fmt.Errorf("field path %s: map %s has no key %s", path, name, index)
+			}
+			cur = cur.Map().Get(key)
+			fd = fd.MapValue()
+		default:
+			return nil, fmt.Errorf("field path %s: %s is neither a list nor a map", path, name)
+		}
+		collection = false
+	}
+	if fd == nil {
+		return msg.Interface(), nil
+	}
+	switch {
+	case collection && fd.IsList():
+		list := cur.List()
+		values := make([]any, list.Len())
+		for i := range values {
+			values[i] = protoScalar(fd, list.Get(i))
+		}
+		return values, nil
+	case collection && fd.IsMap():
+		values := map[string]any{}
+		cur.Map().Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
+			values[k.String()] = protoScalar(fd.MapValue(), v)
+			return true
+		})
+		return values, nil
+	}
+	return protoScalar(fd, cur), nil
+}
+
+// protoScalar converts a single value, enums to their name and messages to proto.Message.
+func protoScalar(fd protoreflect.FieldDescriptor, v protoreflect.Value) any {
+	switch fd.Kind() {
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByNumber(v.Enum()); ev != nil {
+			return string(ev.Name())
+		}
+		return int32(v.Enum())
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return v.Message().Interface()
+	}
+	return v.Interface()
+}
+
+func protoMapKey(fd protoreflect.FieldDescriptor, key string) (protoreflect.MapKey, error) {
+	var v protoreflect.Value
+	var err error
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		v = protoreflect.ValueOfString(key)
+	case protoreflect.BoolKind:
+		var b bool
+		b, err = strconv.ParseBool(key)
+		v = protoreflect.ValueOfBool(b)
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		var i int64
+		i, err = strconv.ParseInt(key, 10, 32)
+		v = protoreflect.ValueOfInt32(int32(i))
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		var i int64
+		i, err = strconv.ParseInt(key, 10, 64)
+		v = protoreflect.ValueOfInt64(i)
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		var u uint64
+		u, err = strconv.ParseUint(key, 10, 32)
+		v = protoreflect.ValueOfUint32(uint32(u))
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		var u uint64
+		u, err = strconv.ParseUint(key, 10, 64)
+		v = protoreflect.ValueOfUint64(u)
+	default:
+		return protoreflect.MapKey{}, fmt.Errorf("unsupported map key kind %s", fd.Kind())
+	}
+	if err != nil {
+		return protoreflect.MapKey{}, fmt.Errorf("invalid map key %s: %w", key, err)
+	}
+	return v.MapKey(), nil
+}
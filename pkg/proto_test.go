@@ -0,0 +1,121 @@
+package goe2e_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	goe2e "github.com/J-Bockhofer/goe2e/pkg"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// The well-known descriptor and struct types stand in for generated messages.
+var protoPerson = &descriptorpb.DescriptorProto{
+	Name: proto.String("Person"),
+	Field: []*descriptorpb.FieldDescriptorProto{
+		{Name: proto.String("name"), Number: proto.Int32(1), Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum()},
+		{Name: proto.String("age"), Number: proto.Int32(2), Type: descriptorpb.FieldDescriptorProto_TYPE_INT32.Enum()},
+	},
+	Options: &descriptorpb.MessageOptions{Deprecated: proto.Bool(true)},
+}
+
+func newProtoServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req descriptorpb.DescriptorProto
+		b, _ := io.ReadAll(r.Body)
+		if r.Header.Get("Content-Type") != goe2e.ContentHeaderProtobuf || proto.Unmarshal(b, &req) != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		resp := proto.Clone(protoPerson).(*descriptorpb.DescriptorProto)
+		resp.Name = req.Name
+		b, _ = proto.Marshal(resp)
+		w.Header().Set("Content-Type", goe2e.ContentHeaderProtobuf)
+		w.Write(b)
+	}))
+}
+
+func TestProto(t *testing.T) {
+	srv := newProtoServer()
+	defer srv.Close()
+
+	goe2e.TestRequest(t, &goe2e.TestConfig{
+		Name: "POST /person",
+		SpecOpts: []goe2e.SpecOption{
+			goe2e.WithMethod(http.MethodPost),
+			goe2e.WithUrl(srv.URL),
+			goe2e.WithProto(&descriptorpb.DescriptorProto{Name: proto.String("Person")}),
+		},
+		PostTestStatements: []goe2e.TestStatement{
+			{Description: "status 200", Statement: goe2e.TestStatusCode(http.StatusOK)},
+			{Description: "name", Statement: goe2e.TestProtoField[descriptorpb.DescriptorProto]("name", "Person")},
+			{Description: "field name", Statement: goe2e.TestProtoField[descriptorpb.DescriptorProto]("field[1].name", "age")},
+			{Description: "number", Statement: goe2e.TestProtoField[descriptorpb.DescriptorProto]("field[0].number", 1)},
+			{Description: "enum", Statement: goe2e.TestProtoField[descriptorpb.DescriptorProto]("field[1].type", "TYPE_INT32")},
+			{Description: "nested", Statement: goe2e.TestProtoField[descriptorpb.DescriptorProto]("options.deprecated", true)},
+			{Description: "message", Statement: goe2e.TestProtoField[descriptorpb.DescriptorProto]("options",
+				&descriptorpb.MessageOptions{Deprecated: proto.Bool(true)})},
+			{Description: "equal", Statement: goe2e.TestProtoEqual(protoPerson)},
+			{Description: "decode", Statement: func(t *testing.T, rh *goe2e.RequestHandler) {
+				msg, err := goe2e.DecodeProtoResponse[descriptorpb.DescriptorProto](rh)
+				if assert.NoError(t, err) {
+					assert.Len(t, msg.GetField(), 2)
+				}
+			}},
+		},
+	})
+}
+
+func TestProtoFieldPaths(t *testing.T) {
+	s, err := structpb.NewStruct(map[string]any{
+		"tags": []any{"a", "b"},
+		"user": map[string]any{"name": "john"},
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	b, err := proto.Marshal(s)
+	if !assert.NoError(t, err) {
+		return
+	}
+	rh := &goe2e.RequestHandler{ResponseBody: b}
+
+	testCases := []struct {
+		name     string
+		path     string
+		expected any
+		ok       bool
+	}{
+		{"Map entry", "fields[user].struct_value.fields[name].string_value", "john", true},
+		{"JSON name", "fields[user].structValue.fields[name].stringValue", "john", true},
+		{"List element", "fields[tags].list_value.values[1].string_value", "b", true},
+		{"Wrong value", "fields[user].struct_value.fields[name].string_value", "jane", false},
+		{"Missing key", "fields[missing].string_value", "", false},
+		{"Index out of range", "fields[tags].list_value.values[2].string_value", "", false},
+		{"Unknown field", "fields[user].unknown", "", false},
+		{"Index on scalar", "fields[user].struct_value.fields[name].string_value[0]", "", false},
+		{"Field of collection", "fields.name", "", false},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &testing.T{}
+			goe2e.TestProtoField[structpb.Struct](tt.path, tt.expected)(mock, rh)
+			assert.Equal(t, tt.ok, !mock.Failed())
+		})
+	}
+
+	t.Run("Not equal", func(t *testing.T) {
+		mock := &testing.T{}
+		goe2e.TestProtoEqual(&structpb.Struct{})(mock, rh)
+		assert.True(t, mock.Failed())
+	})
+
+	t.Run("JSON form", func(t *testing.T) {
+		assert.Equal(t, "{\n  \"tags\": [\n    \"a\",\n    \"b\"\n  ],\n  \"user\": {\n    \"name\": \"john\"\n  }\n}", goe2e.ProtoJSON(s))
+	})
+}
@@ -1,6 +1,9 @@
 package goe2e
 
 import (
+	"bytes"
+	"fmt"
+	"io"
 	"log"
 	"log/slog"
 	"net/http"
@@ -21,6 +24,32 @@ func WithHeaders(headers D) RequestModifier {
 	}
 }
 
+// requestBody reads the body of a http.Request without consuming it, by using http.Request.GetBody.
+// Requests generated by a Spec always support this.
+func requestBody(r *http.Request) ([]byte, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return []byte{}, nil
+	}
+	if r.GetBody == nil {
+		return nil, fmt.Errorf("request body can not be read without consuming it")
+	}
+	rc, err := r.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// setRequestBody replaces the body of a http.Request, keeping it re-readable.
+func setRequestBody(r *http.Request, body []byte) {
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	r.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	r.ContentLength = int64(len(body))
+}
+
 const (
 	ContentHeaderJSON string = "application/json"
 )
@@ -45,7 +74,7 @@ func WithTimeToFirstByte() RequestModifier {
 		tr := r.Clone(httptrace.WithClientTrace(r.Context(), trace))
 		start = time.Now()
 		if _, err := http.DefaultTransport.RoundTrip(tr); err != nil {
-			log.Fatal(err)
+			log.Fatal(DefaultSecrets.Redact(err.Error()))
 		}
 		slog.Info("Total time: " + time.Since(start).String())
 		return nil
@@ -11,16 +11,25 @@ type ResponseModifier func(*http.Response) error
 // It is separate to the ResponseModifier because once the io.ReadCloser of the http.Response is read, there is no putting the body back in.
 type ResponseBodyModifier func([]byte) ([]byte, error)
 
-// ResponseJSONToEnv parses the ResponseBody (JSON) into a map[string]interface{}.
+// ResponseJSONToEnv parses the ResponseBody (JSON) into a map[string]interface{} and writes the values of all keys defined in env back to env.
 // Optionally takes a keymap (or nil) that holds a mapping from the key literal to a key literal in the json encoded body.
 // If the env key is not found in the keymap, the env key itself is used instead, as if not passing a keymap at all.
 func ResponseJSONToEnv(env H, keymap D) ResponseBodyModifier {
+	return responseJSONToEnv(mapEnv(env), keymap)
+}
+
+// ResponseJSONToEnv is the Env counterpart of the ResponseJSONToEnv function, the values are set in the scope it is called on.
+func (e *Env) ResponseJSONToEnv(keymap D) ResponseBodyModifier {
+	return responseJSONToEnv(e, keymap)
+}
+
+func responseJSONToEnv(store envStore, keymap D) ResponseBodyModifier {
 	return func(body []byte) ([]byte, error) {
 		bodyMap, err := bodyJSONToMap(body)
 		if err != nil {
 			return nil, err
 		}
-		for k := range env {
+		for _, k := range store.keys() {
 			keyInBody := k
 			if keymap != nil {
 				var ok bool
@@ -33,7 +42,7 @@ func ResponseJSONToEnv(env H, keymap D) ResponseBodyModifier {
 			if valInBody == nil {
 				continue
 			}
-			env[k] = valInBody
+			store.set(k, valInBody, "response")
 		}
 		return body, nil
 	}
@@ -0,0 +1,146 @@
+package goe2e
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// RedactedPlaceholder replaces secret values in all output.
+const RedactedPlaceholder = "[REDACTED]"
+
+// minSecretLength keeps very short values from being registered, as redacting them would garble the output.
+const minSecretLength = 4
+
+// DefaultSecrets is the registry used for redacting failure messages, logs and curl exports.
+// Values marked sensitive in an Env are registered here.
+var DefaultSecrets = NewSecrets()
+
+// Secrets is a registry of sensitive values and header names.
+type Secrets struct {
+	mu      sync.RWMutex
+	values  map[string]struct{}
+	headers map[string]struct{}
+}
+
+// NewSecrets creates a registry that treats the Authorization, Proxy-Authorization, Cookie, Set-Cookie and X-Api-Key headers as sensitive.
+func NewSecrets() *Secrets {
+	s := &Secrets{
+		values:  map[string]struct{}{},
+		headers: map[string]struct{}{},
+	}
+	s.AddHeader("Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key")
+	return s
+}
+
+// Add registers values as secret. Values shorter than four characters are ignored.
+func (s *Secrets) Add(values ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if len(v) < minSecretLength {
+			continue
+		}
+		s.values[v] = struct{}{}
+	}
+}
+
+// AddHeader marks the headers as sensitive, their values are redacted and registered as secrets when seen.
+func (s *Secrets) AddHeader(names ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, n := range names {
+		s.headers[http.CanonicalHeaderKey(n)] = struct{}{}
+	}
+}
+
+// IsSensitiveHeader reports whether the header name is marked as sensitive.
+func (s *Secrets) IsSensitiveHeader(name string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.headers[http.CanonicalHeaderKey(name)]
+	return ok
+}
+
+// AddFromHeader registers the values of all sensitive headers in h as secrets.
+// Credentials following an auth scheme, like the token of "Bearer <token>", and single cookie values are registered on their own as well.
+func (s *Secrets) AddFromHeader(h http.Header) {
+	for name, vals := range h {
+		if !s.IsSensitiveHeader(name) {
+			continue
+		}
+		for _, v := range vals {
+			s.Add(v)
+			if _, cred, ok := strings.Cut(v, " "); ok {
+				s.Add(cred)
+			}
+			if name == "Cookie" || name == "Set-Cookie" {
+				for _, c := range strings.Split(v, ";") {
+					if _, val, ok := strings.Cut(c, "="); ok {
+						s.Add(val)
+					}
+				}
+			}
+		}
+	}
+}
+
+// Redact replaces all registered secret values in str with the RedactedPlaceholder.
+func (s *Secrets) Redact(str string) string {
+	s.mu.RLock()
+	values := make([]string, 0, len(s.values))
+	for v := range s.values {
+		values = append(values, v)
+	}
+	s.mu.RUnlock()
+	// longest first, so secrets containing other secrets are replaced as a whole
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+	for _, v := range values {
+		str = strings.ReplaceAll(str, v, RedactedPlaceholder)
+	}
+	return str
+}
+
+// RedactHeader returns a copy of h with the values of sensitive headers replaced and secrets redacted from all others.
+func (s *Secrets) RedactHeader(h http.Header) http.Header {
+	out := make(http.Header, len(h))
+	for name, vals := range h {
+		redacted := make([]string, len(vals))
+		for i, v := range vals {
+			if s.IsSensitiveHeader(name) {
+				redacted[i] = RedactedPlaceholder
+			} else {
+				redacted[i] = s.Redact(v)
+			}
+		}
+		out[name] = redacted
+	}
+	return out
+}
+
+// SecretFromFile reads a secret from a file, e.g. a mounted CI secret, and registers it with the DefaultSecrets.
+// Surrounding whitespace, like a trailing newline, is trimmed.
+func SecretFromFile(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("reading secret failed: %w", err)
+	}
+	secret := strings.TrimSpace(string(b))
+	DefaultSecrets.Add(secret)
+	return secret, nil
+}
+
+// SecretFromOSEnv reads a secret from an OS environment variable and registers it with the DefaultSecrets.
+// Returns an error if the variable is not set.
+func SecretFromOSEnv(name string) (string, error) {
+	secret, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("reading secret failed: environment variable %s not set", name)
+	}
+	DefaultSecrets.Add(secret)
+	return secret, nil
+}
@@ -0,0 +1,95 @@
+package goe2e_test
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	goe2e "github.com/J-Bockhofer/goe2e/pkg"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecretsRedact(t *testing.T) {
+	s := goe2e.NewSecrets()
+	s.Add("hunter2hunter2", "abc", "hunter2")
+
+	testCases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"No secret", "all good", "all good"},
+		{"Single", "password is hunter2", "password is [REDACTED]"},
+		{"Longest first", "token=hunter2hunter2", "token=[REDACTED]"},
+		{"Short values are ignored", "abc", "abc"},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, s.Redact(tt.input))
+		})
+	}
+}
+
+func TestSecretsHeaders(t *testing.T) {
+	s := goe2e.NewSecrets()
+	s.AddHeader("X-Session")
+	h := http.Header{}
+	h.Set("Authorization", "Bearer s3cr3t-token")
+	h.Set("Cookie", "session=cookie-value; theme=dark")
+	h.Set("X-Session", "session-id")
+	h.Set("X-Echo", "got s3cr3t-token")
+	h.Set("Accept", "application/json")
+
+	assert.True(t, s.IsSensitiveHeader("authorization"))
+	assert.False(t, s.IsSensitiveHeader("Accept"))
+
+	s.AddFromHeader(h)
+	assert.Equal(t, "token [REDACTED]", s.Redact("token s3cr3t-token"))
+	assert.Equal(t, "[REDACTED]", s.Redact("cookie-value"))
+
+	redacted := s.RedactHeader(h)
+	assert.Equal(t, goe2e.RedactedPlaceholder, redacted.Get("Authorization"))
+	assert.Equal(t, goe2e.RedactedPlaceholder, redacted.Get("X-Session"))
+	assert.Equal(t, "got [REDACTED]", redacted.Get("X-Echo"))
+	assert.Equal(t, "application/json", redacted.Get("Accept"))
+	assert.Equal(t, "Bearer s3cr3t-token", h.Get("Authorization"))
+}
+
+func TestSecretSources(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte("file-secret-value\n"), 0o600); err != nil {
+		t.Fatalf("could not write secret file: %s", err.Error())
+	}
+	t.Setenv("GOE2E_TEST_SECRET", "os-secret-value")
+
+	secret, err := goe2e.SecretFromFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "file-secret-value", secret)
+
+	secret, err = goe2e.SecretFromOSEnv("GOE2E_TEST_SECRET")
+	assert.NoError(t, err)
+	assert.Equal(t, "os-secret-value", secret)
+
+	_, err = goe2e.SecretFromOSEnv("GOE2E_TEST_SECRET_MISSING")
+	assert.Error(t, err)
+
+	assert.Equal(t, "[REDACTED] [REDACTED]", goe2e.DefaultSecrets.Redact("file-secret-value os-secret-value"))
+}
+
+func TestEnvSecrets(t *testing.T) {
+	env := goe2e.NewEnv()
+	env.SetSecret("token", "env-secret-token")
+	scope := env.Scope("step")
+	scope.Set("token", "rotated-secret-token")
+
+	v, err := scope.String("token")
+	assert.NoError(t, err)
+	assert.Equal(t, "rotated-secret-token", v)
+	assert.True(t, scope.IsSecret("token"))
+	for _, change := range env.History() {
+		assert.Equal(t, goe2e.RedactedPlaceholder, change.New)
+	}
+	assert.Equal(t, "[REDACTED]", goe2e.DefaultSecrets.Redact("rotated-secret-token"))
+}
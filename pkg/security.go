@@ -0,0 +1,290 @@
+package goe2e
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Severity ranks the findings of a security audit.
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityLow
+	SeverityMedium
+	SeverityHigh
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "INFO"
+	case SeverityLow:
+		return "LOW"
+	case SeverityMedium:
+		return "MEDIUM"
+	case SeverityHigh:
+		return "HIGH"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// Finding is a single weakness found by an AuditCheck.
+type Finding struct {
+	Check    string
+	Severity Severity
+	Message  string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("[%s] %s: %s", f.Severity, f.Check, f.Message)
+}
+
+// AuditCheck inspects a response for one class of weaknesses, returning nothing if there are none.
+type AuditCheck func(*http.Response) []Finding
+
+// AuditReport holds the findings of all checks of an audit, ordered by descending severity.
+type AuditReport []Finding
+
+// Audit runs all checks on the response, or the DefaultSecurityAudit if none are passed, and collects their findings.
+func Audit(resp *http.Response, checks ...AuditCheck) AuditReport {
+	if len(checks) == 0 {
+		checks = DefaultSecurityAudit()
+	}
+	var report AuditReport
+	for _, check := range checks {
+		report = append(report, check(resp)...)
+	}
+	sort.SliceStable(report, func(i, j int) bool {
+		return report[i].Severity > report[j].Severity
+	})
+	return report
+}
+
+// AtLeast returns the findings with at least the given severity.
+func (ar AuditReport) AtLeast(min Severity) AuditReport {
+	var findings AuditReport
+	for _, f := range ar {
+		if f.Severity >= min {
+			findings = append(findings, f)
+		}
+	}
+	return findings
+}
+
+// String renders the report with one finding per line.
+func (ar AuditReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "security audit: %d findings", len(ar))
+	for _, f := range ar {
+		b.WriteString("\n  " + f.String())
+	}
+	return b.String()
+}
+
+// DefaultSecurityAudit returns all checks with defaults following current recommendations:
+// HSTS for at least 180 days, TLS 1.2 or later and certificates valid for at least 14 more days.
+func DefaultSecurityAudit() []AuditCheck {
+	return []AuditCheck{
+		AuditHSTS(180 * 24 * time.Hour),
+		AuditCSP(),
+		AuditContentTypeOptions(),
+		AuditFrameOptions(),
+		AuditReferrerPolicy(),
+		AuditCookies(),
+		AuditServerHeaders(),
+		AuditTLS(tls.VersionTLS12, 14*24*time.Hour),
+	}
+}
+
+// TestSecurityAudit audits the response with the checks, or the DefaultSecurityAudit if none are passed.
+// It fails once with the whole report if any finding has at least the severity min, otherwise the findings are only logged.
+func TestSecurityAudit(min Severity, checks ...AuditCheck) func(*testing.T, *RequestHandler) {
+	return func(t *testing.T, rh *RequestHandler) {
+		report := Audit(rh.Response, checks...)
+		if len(report.AtLeast(min)) > 0 {
+			assert.Fail(t, DefaultSecrets.Redact(report.String()))
+			return
+		}
+		if len(report) > 0 {
+			t.Log(DefaultSecrets.Redact(report.String()))
+		}
+	}
+}
+
+// isHTTPS reports whether the response was received over TLS.
+func isHTTPS(resp *http.Response) bool {
+	return resp.TLS != nil || (resp.Request != nil && resp.Request.URL.Scheme == "https")
+}
+
+// AuditHSTS checks that responses received over TLS set Strict-Transport-Security with a max-age of at least min.
+func AuditHSTS(min time.Duration) AuditCheck {
+	return func(resp *http.Response) []Finding {
+		if !isHTTPS(resp) {
+			return nil
+		}
+		v := resp.Header.Get("Strict-Transport-Security")
+		if v == "" {
+			return []Finding{{"hsts", SeverityMedium, "Strict-Transport-Security is missing"}}
+		}
+		maxAge := ""
+		for _, directive := range strings.Split(v, ";") {
+			name, arg, _ := strings.Cut(strings.TrimSpace(directive), "=")
+			if strings.EqualFold(name, "max-age") {
+				maxAge = strings.Trim(arg, `"`)
+			}
+		}
+		seconds, err := strconv.Atoi(maxAge)
+		if err != nil {
+			return []Finding{{"hsts", SeverityMedium, fmt.Sprintf("Strict-Transport-Security %q has no valid max-age", v)}}
+		}
+		if age := time.Duration(seconds) * time.Second; age < min {
+			return []Finding{{"hsts", SeverityLow, fmt.Sprintf("Strict-Transport-Security max-age is %s, expected at least %s", age, min)}}
+		}
+		return nil
+	}
+}
+
+// AuditCSP checks that a Content-Security-Policy is set and does not allow unsafe inline scripts or eval.
+func AuditCSP() AuditCheck {
+	return func(resp *http.Response) []Finding {
+		v := resp.Header.Get("Content-Security-Policy")
+		if v == "" {
+			return []Finding{{"csp", SeverityMedium, "Content-Security-Policy is missing"}}
+		}
+		var findings []Finding
+		for _, source := range []string{"'unsafe-inline'", "'unsafe-eval'"} {
+			if strings.Contains(v, source) {
+				findings = append(findings, Finding{"csp", SeverityLow, fmt.Sprintf("Content-Security-Policy allows %s", source)})
+			}
+		}
+		return findings
+	}
+}
+
+// AuditContentTypeOptions checks that X-Content-Type-Options disables MIME type sniffing.
+func AuditContentTypeOptions() AuditCheck {
+	return func(resp *http.Response) []Finding {
+		if !strings.EqualFold(resp.Header.Get("X-Content-Type-Options"), "nosniff") {
+			return []Finding{{"content-type-options", SeverityLow, fmt.Sprintf("X-Content-Type-Options is %q, expected \"nosniff\"", resp.Header.Get("X-Content-Type-Options"))}}
+		}
+		return nil
+	}
+}
+
+// AuditFrameOptions checks that framing by other sites is prevented by X-Frame-Options or the CSP directive frame-ancestors.
+func AuditFrameOptions() AuditCheck {
+	return func(resp *http.Response) []Finding {
+		if strings.Contains(resp.Header.Get("Content-Security-Policy"), "frame-ancestors") {
+			return nil
+		}
+		v := resp.Header.Get("X-Frame-Options")
+		switch strings.ToUpper(v) {
+		case "DENY", "SAMEORIGIN":
+			return nil
+		case "":
+			return []Finding{{"frame-options", SeverityMedium, "neither X-Frame-Options nor CSP frame-ancestors prevent clickjacking"}}
+		default:
+			return []Finding{{"frame-options", SeverityMedium, fmt.Sprintf("X-Frame-Options %q is not DENY or SAMEORIGIN", v)}}
+		}
+	}
+}
+
+// AuditReferrerPolicy checks that a Referrer-Policy is set that does not leak full URLs to other origins.
+func AuditReferrerPolicy() AuditCheck {
+	return func(resp *http.Response) []Finding {
+		v := resp.Header.Get("Referrer-Policy")
+		if v == "" {
+			return []Finding{{"referrer-policy", SeverityLow, "Referrer-Policy is missing"}}
+		}
+		// the last known policy of a list is applied
+		policies := strings.Split(v, ",")
+		switch policy := strings.ToLower(strings.TrimSpace(policies[len(policies)-1])); policy {
+		case "unsafe-url", "no-referrer-when-downgrade":
+			return []Finding{{"referrer-policy", SeverityLow, fmt.Sprintf("Referrer-Policy %q sends full URLs to other origins", policy)}}
+		}
+		return nil
+	}
+}
+
+// AuditCookies checks the flags of all cookies set by the response: Secure over TLS, HttpOnly and SameSite.
+func AuditCookies() AuditCheck {
+	return func(resp *http.Response) []Finding {
+		var findings []Finding
+		for _, c := range resp.Cookies() {
+			if !c.Secure && isHTTPS(resp) {
+				findings = append(findings, Finding{"cookies", SeverityMedium, fmt.Sprintf("cookie %s is not Secure", c.Name)})
+			}
+			if !c.HttpOnly {
+				findings = append(findings, Finding{"cookies", SeverityLow, fmt.Sprintf("cookie %s is not HttpOnly", c.Name)})
+			}
+			switch {
+			case c.SameSite == 0 || c.SameSite == http.SameSiteDefaultMode:
+				findings = append(findings, Finding{"cookies", SeverityLow, fmt.Sprintf("cookie %s has no SameSite attribute", c.Name)})
+			case c.SameSite == http.SameSiteNoneMode && !c.Secure:
+				findings = append(findings, Finding{"cookies", SeverityMedium, fmt.Sprintf("cookie %s has SameSite None without Secure, which browsers reject", c.Name)})
+			}
+		}
+		return findings
+	}
+}
+
+// versionHeaders are response headers commonly disclosing the server software.
+var versionHeaders = []string{"Server", "X-Powered-By", "X-AspNet-Version", "X-AspNetMvc-Version", "X-Generator"}
+
+// AuditServerHeaders checks that the response does not disclose the versions of the server software.
+func AuditServerHeaders() AuditCheck {
+	return func(resp *http.Response) []Finding {
+		var findings []Finding
+		for _, name := range versionHeaders {
+			v := resp.Header.Get(name)
+			switch {
+			case v == "":
+			case strings.ContainsAny(v, "0123456789"):
+				findings = append(findings, Finding{"server-headers", SeverityLow, fmt.Sprintf("%s %q discloses a version", name, v)})
+			case name != "Server":
+				findings = append(findings, Finding{"server-headers", SeverityInfo, fmt.Sprintf("%s %q discloses the software", name, v)})
+			}
+		}
+		return findings
+	}
+}
+
+// AuditTLS checks the connection the response was received on: the protocol version is at least minVersion, like tls.VersionTLS12,
+// the cipher suite is not considered insecure and the certificate stays valid for at least validFor.
+func AuditTLS(minVersion uint16, validFor time.Duration) AuditCheck {
+	return func(resp *http.Response) []Finding {
+		state := resp.TLS
+		if state == nil {
+			return []Finding{{"tls", SeverityHigh, "response was not received over TLS"}}
+		}
+		var findings []Finding
+		if state.Version < minVersion {
+			findings = append(findings, Finding{"tls", SeverityHigh, fmt.Sprintf("protocol %s is older than %s", tls.VersionName(state.Version), tls.VersionName(minVersion))})
+		}
+		for _, suite := range tls.InsecureCipherSuites() {
+			if suite.ID == state.CipherSuite {
+				findings = append(findings, Finding{"tls", SeverityHigh, fmt.Sprintf("cipher suite %s is insecure", suite.Name)})
+			}
+		}
+		if len(state.PeerCertificates) > 0 {
+			cert := state.PeerCertificates[0]
+			switch left := time.Until(cert.NotAfter); {
+			case left <= 0:
+				findings = append(findings, Finding{"tls", SeverityHigh, fmt.Sprintf("certificate expired at %s", cert.NotAfter.Format(time.RFC3339))})
+			case left < validFor:
+				findings = append(findings, Finding{"tls", SeverityMedium, fmt.Sprintf("certificate expires at %s, within %s", cert.NotAfter.Format(time.RFC3339), validFor)})
+			}
+		}
+		return findings
+	}
+}
@@ -0,0 +1,117 @@
+package goe2e_test
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	goe2e "github.com/J-Bockhofer/goe2e/pkg"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func hardenedHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Strict-Transport-Security", "max-age=31536000; includeSubDomains")
+	w.Header().Set("Content-Security-Policy", "default-src 'self'; frame-ancestors 'none'")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Referrer-Policy", "strict-origin-when-cross-origin")
+	w.Header().Set("Server", "nginx")
+	http.SetCookie(w, &http.Cookie{Name: "session", Value: "s", Secure: true, HttpOnly: true, SameSite: http.SameSiteLaxMode})
+	w.Write([]byte(`{}`))
+}
+
+func leakyHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Security-Policy", "script-src 'self' 'unsafe-inline'")
+	w.Header().Set("X-Frame-Options", "ALLOW-FROM https://example.com")
+	w.Header().Set("Referrer-Policy", "no-referrer, unsafe-url")
+	w.Header().Set("Server", "Apache/2.4.1")
+	w.Header().Set("X-Powered-By", "Express")
+	http.SetCookie(w, &http.Cookie{Name: "tracking", Value: "t", SameSite: http.SameSiteNoneMode})
+	http.SetCookie(w, &http.Cookie{Name: "prefs", Value: "p", HttpOnly: true})
+	w.Write([]byte(`{}`))
+}
+
+func TestSecurityAudit(t *testing.T) {
+	tlsSrv := httptest.NewTLSServer(http.HandlerFunc(hardenedHandler))
+	defer tlsSrv.Close()
+
+	goe2e.TestRequest(t, &goe2e.TestConfig{
+		Name:        "GET hardened",
+		SpecOpts:    []goe2e.SpecOption{goe2e.WithUrl(tlsSrv.URL)},
+		HandlerOpts: []goe2e.RequestHandlerOption{goe2e.WithClient(tlsSrv.Client())},
+		PostTestStatements: []goe2e.TestStatement{
+			{Description: "audit", Statement: goe2e.TestSecurityAudit(goe2e.SeverityInfo)},
+			{Description: "report", Statement: func(t *testing.T, rh *goe2e.RequestHandler) {
+				assert.Empty(t, goe2e.Audit(rh.Response))
+			}},
+		},
+	})
+
+	plainSrv := httptest.NewServer(http.HandlerFunc(leakyHandler))
+	defer plainSrv.Close()
+
+	goe2e.TestRequest(t, &goe2e.TestConfig{
+		Name:     "GET leaky",
+		SpecOpts: []goe2e.SpecOption{goe2e.WithUrl(plainSrv.URL)},
+		PostTestStatements: []goe2e.TestStatement{
+			{Description: "report", Statement: func(t *testing.T, rh *goe2e.RequestHandler) {
+				report := goe2e.Audit(rh.Response)
+				assert.Equal(t, goe2e.AuditReport{
+					{Check: "tls", Severity: goe2e.SeverityHigh, Message: "response was not received over TLS"},
+					{Check: "frame-options", Severity: goe2e.SeverityMedium, Message: `X-Frame-Options "ALLOW-FROM https://example.com" is not DENY or SAMEORIGIN`},
+					{Check: "cookies", Severity: goe2e.SeverityMedium, Message: "cookie tracking has SameSite None without Secure, which browsers reject"},
+					{Check: "csp", Severity: goe2e.SeverityLow, Message: "Content-Security-Policy allows 'unsafe-inline'"},
+					{Check: "content-type-options", Severity: goe2e.SeverityLow, Message: `X-Content-Type-Options is "", expected "nosniff"`},
+					{Check: "referrer-policy", Severity: goe2e.SeverityLow, Message: `Referrer-Policy "unsafe-url" sends full URLs to other origins`},
+					{Check: "cookies", Severity: goe2e.SeverityLow, Message: "cookie tracking is not HttpOnly"},
+					{Check: "cookies", Severity: goe2e.SeverityLow, Message: "cookie prefs has no SameSite attribute"},
+					{Check: "server-headers", Severity: goe2e.SeverityLow, Message: `Server "Apache/2.4.1" discloses a version`},
+					{Check: "server-headers", Severity: goe2e.SeverityInfo, Message: `X-Powered-By "Express" discloses the software`},
+				}, report)
+				assert.Len(t, report.AtLeast(goe2e.SeverityMedium), 3)
+				assert.True(t, strings.HasPrefix(report.String(), "security audit: 10 findings\n  [HIGH] tls: response was not received over TLS\n"))
+			}},
+			{Description: "selected checks", Statement: goe2e.TestSecurityAudit(goe2e.SeverityMedium, goe2e.AuditHSTS(time.Hour), goe2e.AuditServerHeaders())},
+		},
+	})
+}
+
+func TestAuditTLS(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(hardenedHandler))
+	srv.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+	srv.StartTLS()
+	defer srv.Close()
+
+	rh, err := goe2e.RunConfig(&goe2e.TestConfig{
+		Name:        "GET tls 1.2",
+		SpecOpts:    []goe2e.SpecOption{goe2e.WithUrl(srv.URL)},
+		HandlerOpts: []goe2e.RequestHandlerOption{goe2e.WithClient(srv.Client())},
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	testCases := []struct {
+		name     string
+		check    goe2e.AuditCheck
+		expected []string
+	}{
+		{"TLS 1.2 allowed", goe2e.AuditTLS(tls.VersionTLS12, 0), nil},
+		{"TLS 1.3 required", goe2e.AuditTLS(tls.VersionTLS13, 0), []string{"[HIGH] tls: protocol TLS 1.2 is older than TLS 1.3"}},
+		{"Certificate expiring", goe2e.AuditTLS(tls.VersionTLS12, 200*365*24*time.Hour), []string{"[MEDIUM] tls: certificate expires at"}},
+		{"HSTS too short", goe2e.AuditHSTS(2 * 365 * 24 * time.Hour), []string{"[LOW] hsts: Strict-Transport-Security max-age is 8760h0m0s"}},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			report := goe2e.Audit(rh.Response, tt.check)
+			if !assert.Len(t, report, len(tt.expected)) {
+				return
+			}
+			for i, prefix := range tt.expected {
+				assert.True(t, strings.HasPrefix(report[i].String(), prefix), report[i].String())
+			}
+		})
+	}
+}
@@ -0,0 +1,286 @@
+package goe2e
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Session owns a http.Client with a cookie jar, so cookies persist across all TestConfigs using it, like in a browser.
+// It also remembers the last cookie of each name set by a server, including the attributes a cookie jar drops.
+type Session struct {
+	Client *http.Client
+
+	mu       sync.Mutex
+	jar      http.CookieJar
+	received map[string]*http.Cookie
+}
+
+// NewSession creates a Session with an empty cookie jar.
+func NewSession() (*Session, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	s := &Session{
+		jar:      jar,
+		received: map[string]*http.Cookie{},
+	}
+	s.Client = &http.Client{Jar: sessionJar{s}}
+	return s, nil
+}
+
+// sessionJar is the jar of the session's client. It guards the session's current jar, so ClearCookies can replace it while requests are running.
+type sessionJar struct {
+	s *Session
+}
+
+func (j sessionJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.s.mu.Lock()
+	defer j.s.mu.Unlock()
+	j.s.jar.SetCookies(u, cookies)
+}
+
+func (j sessionJar) Cookies(u *url.URL) []*http.Cookie {
+	j.s.mu.Lock()
+	defer j.s.mu.Unlock()
+	return j.s.jar.Cookies(u)
+}
+
+// WithSession sends the request with the session's client, storing and sending its cookies.
+func WithSession(s *Session) RequestHandlerOption {
+	return func(rh *RequestHandler) error {
+		if err := WithClient(s.Client)(rh); err != nil {
+			return err
+		}
+		return WithRoundTripper(s.recordCookies)(rh)
+	}
+}
+
+// recordCookies remembers the cookies set by every response, including those of redirects.
+func (s *Session) recordCookies(next http.RoundTripper) http.RoundTripper {
+	return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		resp, err := next.RoundTrip(r)
+		if err != nil {
+			return resp, err
+		}
+		s.mu.Lock()
+		for _, c := range resp.Cookies() {
+			s.received[c.Name] = c
+		}
+		s.mu.Unlock()
+		return resp, nil
+	})
+}
+
+// Cookies returns the cookies the session would send to rawURL.
+func (s *Session) Cookies(rawURL string) ([]*http.Cookie, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	return s.Client.Jar.Cookies(u), nil
+}
+
+// Cookie returns the named cookie the session would send to rawURL.
+func (s *Session) Cookie(rawURL string, name string) (*http.Cookie, error) {
+	cookies, err := s.Cookies(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range cookies {
+		if c.Name == name {
+			return c, nil
+		}
+	}
+	return nil, fmt.Errorf("cookie %s not set for %s", name, rawURL)
+}
+
+// SetCookie stores cookies in the session's jar as if they were set by rawURL.
+func (s *Session) SetCookie(rawURL string, cookies ...*http.Cookie) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	s.Client.Jar.SetCookies(u, cookies)
+	return nil
+}
+
+// ClearCookies empties the cookie jar, e.g. to start a new browser session.
+func (s *Session) ClearCookies() error {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jar = jar
+	s.received = map[string]*http.Cookie{}
+	return nil
+}
+
+// ReceivedCookie returns the last cookie with the given name set by a server, with all its attributes, or nil.
+func (s *Session) ReceivedCookie(name string) *http.Cookie {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.received[name]
+}
+
+// CookieCheck verifies a single property of a cookie.
+type CookieCheck func(*http.Cookie) error
+
+// CookieValue checks the value of the cookie.
+// Cookie values are sensitive like the Set-Cookie header, so both values are registered with the DefaultSecrets and redacted on failure.
+func CookieValue(value string) CookieCheck {
+	return func(c *http.Cookie) error {
+		if c.Value != value {
+			DefaultSecrets.Add(c.Value, value)
+			return fmt.Errorf("cookie %s has value %q, expected %q", c.Name, DefaultSecrets.Redact(c.Value), DefaultSecrets.Redact(value))
+		}
+		return nil
+	}
+}
+
+// CookieSecure checks that the cookie has the Secure attribute.
+func CookieSecure() CookieCheck {
+	return func(c *http.Cookie) error {
+		if !c.Secure {
+			return fmt.Errorf("cookie %s is not Secure", c.Name)
+		}
+		return nil
+	}
+}
+
+// CookieHttpOnly checks that the cookie has the HttpOnly attribute.
+func CookieHttpOnly() CookieCheck {
+	return func(c *http.Cookie) error {
+		if !c.HttpOnly {
+			return fmt.Errorf("cookie %s is not HttpOnly", c.Name)
+		}
+		return nil
+	}
+}
+
+// CookieSameSite checks the SameSite attribute of the cookie.
+func CookieSameSite(mode http.SameSite) CookieCheck {
+	return func(c *http.Cookie) error {
+		if c.SameSite != mode {
+			return fmt.Errorf("cookie %s has SameSite %s, expected %s", c.Name, sameSiteName(c.SameSite), sameSiteName(mode))
+		}
+		return nil
+	}
+}
+
+// CookiePath checks the Path attribute of the cookie.
+func CookiePath(path string) CookieCheck {
+	return func(c *http.Cookie) error {
+		if c.Path != path {
+			return fmt.Errorf("cookie %s has path %q, expected %q", c.Name, c.Path, path)
+		}
+		return nil
+	}
+}
+
+// CookieDomain checks the Domain attribute of the cookie.
+func CookieDomain(domain string) CookieCheck {
+	return func(c *http.Cookie) error {
+		if c.Domain != domain {
+			return fmt.Errorf("cookie %s has domain %q, expected %q", c.Name, c.Domain, domain)
+		}
+		return nil
+	}
+}
+
+// CookieSession checks that the cookie expires with the browser session, i.e. has neither Expires nor Max-Age.
+func CookieSession() CookieCheck {
+	return func(c *http.Cookie) error {
+		if _, ok := cookieExpiry(c); ok {
+			return fmt.Errorf("cookie %s is persistent, expected a session cookie", c.Name)
+		}
+		return nil
+	}
+}
+
+// CookieExpiresWithin checks that the cookie is persistent and expires no later than d from now.
+func CookieExpiresWithin(d time.Duration) CookieCheck {
+	return func(c *http.Cookie) error {
+		expiry, ok := cookieExpiry(c)
+		if !ok {
+			return fmt.Errorf("cookie %s is a session cookie without expiry", c.Name)
+		}
+		if expiry.After(time.Now().Add(d)) {
+			return fmt.Errorf("cookie %s expires at %s, later than %s from now", c.Name, expiry.Format(time.RFC1123), d)
+		}
+		return nil
+	}
+}
+
+// TestCookie asserts that the response sets the named cookie and that it passes all checks.
+func TestCookie(name string, checks ...CookieCheck) func(*testing.T, *RequestHandler) {
+	return func(t *testing.T, rh *RequestHandler) {
+		c := responseCookie(rh.Response, name)
+		if c == nil {
+			assert.Fail(t, fmt.Sprintf("response does not set cookie %s", name))
+			return
+		}
+		for _, check := range checks {
+			if err := check(c); err != nil {
+				assert.Fail(t, err.Error())
+			}
+		}
+	}
+}
+
+// TestCookieCleared asserts that the response deletes the named cookie, by an expiry in the past or a negative Max-Age.
+func TestCookieCleared(name string) func(*testing.T, *RequestHandler) {
+	return func(t *testing.T, rh *RequestHandler) {
+		c := responseCookie(rh.Response, name)
+		if c == nil {
+			assert.Fail(t, fmt.Sprintf("response does not set cookie %s", name))
+			return
+		}
+		expiry, ok := cookieExpiry(c)
+		assert.True(t, ok && !expiry.After(time.Now()), "cookie %s is not cleared", name)
+	}
+}
+
+func responseCookie(resp *http.Response, name string) *http.Cookie {
+	for _, c := range resp.Cookies() {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// cookieExpiry returns when the cookie expires, Max-Age taking precedence over Expires.
+func cookieExpiry(c *http.Cookie) (time.Time, bool) {
+	switch {
+	case c.MaxAge < 0:
+		return time.Unix(0, 0), true
+	case c.MaxAge > 0:
+		return time.Now().Add(time.Duration(c.MaxAge) * time.Second), true
+	case !c.Expires.IsZero():
+		return c.Expires, true
+	}
+	return time.Time{}, false
+}
+
+func sameSiteName(mode http.SameSite) string {
+	switch mode {
+	case http.SameSiteLaxMode:
+		return "Lax"
+	case http.SameSiteStrictMode:
+		return "Strict"
+	case http.SameSiteNoneMode:
+		return "None"
+	default:
+		return "unset"
+	}
+}
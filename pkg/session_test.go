@@ -0,0 +1,150 @@
+package goe2e_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	goe2e "github.com/J-Bockhofer/goe2e/pkg"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newCookieServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{
+			Name: "session", Value: "abc123", Path: "/", MaxAge: 3600,
+			Secure: true, HttpOnly: true, SameSite: http.SameSiteStrictMode,
+		})
+		http.SetCookie(w, &http.Cookie{Name: "theme", Value: "dark", Path: "/"})
+	})
+	mux.HandleFunc("/me", func(w http.ResponseWriter, r *http.Request) {
+		c, err := r.Cookie("session")
+		if err != nil || c.Value != "abc123" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte("john"))
+	})
+	mux.HandleFunc("/logout", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "", Path: "/", MaxAge: -1})
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestSession(t *testing.T) {
+	srv := newCookieServer()
+	defer srv.Close()
+	sess, err := goe2e.NewSession()
+	if !assert.NoError(t, err) {
+		return
+	}
+	route := func(name, path string, statements ...goe2e.TestStatement) *goe2e.TestConfig {
+		return &goe2e.TestConfig{
+			Name:               name,
+			SpecOpts:           []goe2e.SpecOption{goe2e.WithUrl(srv.URL + path)},
+			HandlerOpts:        []goe2e.RequestHandlerOption{goe2e.WithSession(sess)},
+			PostTestStatements: statements,
+		}
+	}
+
+	goe2e.TestRequest(t, route("GET /me before login", "/me",
+		goe2e.TestStatement{Description: "status 401", Statement: goe2e.TestStatusCode(http.StatusUnauthorized)},
+	))
+	goe2e.TestRequest(t, route("GET /login", "/login",
+		goe2e.TestStatement{Description: "session cookie", Statement: goe2e.TestCookie("session",
+			goe2e.CookieValue("abc123"), goe2e.CookieSecure(), goe2e.CookieHttpOnly(),
+			goe2e.CookieSameSite(http.SameSiteStrictMode), goe2e.CookiePath("/"),
+			goe2e.CookieExpiresWithin(2*time.Hour),
+		)},
+		goe2e.TestStatement{Description: "theme cookie", Statement: goe2e.TestCookie("theme", goe2e.CookieSession())},
+	))
+	goe2e.TestRequest(t, route("GET /me after login", "/me",
+		goe2e.TestStatement{Description: "status 200", Statement: goe2e.TestStatusCode(http.StatusOK)},
+	))
+
+	t.Run("Inspect", func(t *testing.T) {
+		c, err := sess.Cookie(srv.URL, "session")
+		assert.NoError(t, err)
+		assert.Equal(t, "abc123", c.Value)
+		received := sess.ReceivedCookie("session")
+		if assert.NotNil(t, received) {
+			assert.True(t, received.HttpOnly)
+			assert.Equal(t, 3600, received.MaxAge)
+		}
+		_, err = sess.Cookie(srv.URL, "missing")
+		assert.Error(t, err)
+	})
+
+	goe2e.TestRequest(t, route("GET /logout", "/logout",
+		goe2e.TestStatement{Description: "session cleared", Statement: goe2e.TestCookieCleared("session")},
+	))
+	goe2e.TestRequest(t, route("GET /me after logout", "/me",
+		goe2e.TestStatement{Description: "status 401", Statement: goe2e.TestStatusCode(http.StatusUnauthorized)},
+	))
+
+	t.Run("Set and clear", func(t *testing.T) {
+		assert.NoError(t, sess.SetCookie(srv.URL, &http.Cookie{Name: "session", Value: "abc123", Path: "/"}))
+		goe2e.TestRequest(t, route("GET /me with set cookie", "/me",
+			goe2e.TestStatement{Description: "status 200", Statement: goe2e.TestStatusCode(http.StatusOK)},
+		))
+		assert.NoError(t, sess.ClearCookies())
+		cookies, err := sess.Cookies(srv.URL)
+		assert.NoError(t, err)
+		assert.Empty(t, cookies)
+		assert.Nil(t, sess.ReceivedCookie("session"))
+	})
+
+	t.Run("Clear while requests run", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for range 4 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				rh, err := goe2e.NewRequestHandler(goe2e.WithSpecOpts(goe2e.WithUrl(srv.URL+"/login")), goe2e.WithSession(sess))
+				if assert.NoError(t, err) {
+					assert.NoError(t, rh.RunRequest())
+				}
+			}()
+		}
+		assert.NoError(t, sess.ClearCookies())
+		wg.Wait()
+	})
+}
+
+func TestCookieChecks(t *testing.T) {
+	c := &http.Cookie{Name: "id", Value: "1", Path: "/app", Domain: "example.com", SameSite: http.SameSiteLaxMode}
+	testCases := []struct {
+		name  string
+		check goe2e.CookieCheck
+		ok    bool
+	}{
+		{"Value", goe2e.CookieValue("1"), true},
+		{"Wrong value", goe2e.CookieValue("2"), false},
+		{"Not secure", goe2e.CookieSecure(), false},
+		{"Not http only", goe2e.CookieHttpOnly(), false},
+		{"SameSite", goe2e.CookieSameSite(http.SameSiteLaxMode), true},
+		{"Wrong SameSite", goe2e.CookieSameSite(http.SameSiteNoneMode), false},
+		{"Path", goe2e.CookiePath("/app"), true},
+		{"Domain", goe2e.CookieDomain("example.com"), true},
+		{"Session", goe2e.CookieSession(), true},
+		{"No expiry", goe2e.CookieExpiresWithin(time.Hour), false},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.ok, tt.check(c) == nil)
+		})
+	}
+
+	t.Run("Wrong value is redacted", func(t *testing.T) {
+		session := &http.Cookie{Name: "session", Value: "session-id-8841"}
+		err := goe2e.CookieValue("session-id-1377")(session)
+		if assert.Error(t, err) {
+			assert.NotContains(t, err.Error(), "session-id-8841")
+			assert.NotContains(t, err.Error(), "session-id-1377")
+		}
+	})
+}
@@ -0,0 +1,142 @@
+package goe2e_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"testing"
+	"time"
+
+	goe2e "github.com/J-Bockhofer/goe2e/pkg"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithHMACSignature(t *testing.T) {
+	key := []byte("webhook-key")
+	ts := time.Unix(1700000000, 0)
+	now := func() time.Time { return ts }
+	body := []byte(`{"event":"created"}`)
+	bodySum := sha256.Sum256(body)
+
+	sign := func(h func() []byte) string { return hex.EncodeToString(h()) }
+	mac := func(data string) []byte {
+		m := hmac.New(sha256.New, key)
+		m.Write([]byte(data))
+		return m.Sum(nil)
+	}
+
+	testCases := []struct {
+		name     string
+		cfg      goe2e.HMACConfig
+		header   string
+		expected string
+	}{
+		{"Defaults", goe2e.HMACConfig{
+			Key:   key,
+			Parts: []goe2e.CanonicalPart{goe2e.PartMethod(), goe2e.PartPath(), goe2e.PartQuery(), goe2e.PartBodyHash()},
+		}, "X-Signature", sign(func() []byte {
+			return mac("POST\n/hooks/orders\na=1&b=2\n" + hex.EncodeToString(bodySum[:]))
+		})},
+		{"Timestamp and body with prefix", goe2e.HMACConfig{
+			Key:             key,
+			Parts:           []goe2e.CanonicalPart{goe2e.PartTimestamp(), goe2e.PartBody()},
+			Separator:       ".",
+			Header:          "X-Hub-Signature-256",
+			Prefix:          "sha256=",
+			TimestampHeader: "X-Timestamp",
+			Now:             now,
+		}, "X-Hub-Signature-256", "sha256=" + sign(func() []byte {
+			return mac("1700000000." + string(body))
+		})},
+		{"Header part with SHA-1 and base64", goe2e.HMACConfig{
+			Key:    key,
+			Hash:   sha1.New,
+			Parts:  []goe2e.CanonicalPart{goe2e.PartHeader("X-Request-Id")},
+			Base64: true,
+		}, "X-Signature", func() string {
+			m := hmac.New(sha1.New, key)
+			m.Write([]byte("req-1"))
+			return base64.StdEncoding.EncodeToString(m.Sum(nil))
+		}()},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, err := goe2e.NewSpec(
+				goe2e.WithMethod(http.MethodPost),
+				goe2e.WithUrl("https://example.com/hooks/orders?b=2&a=1"),
+				goe2e.WithBody(body),
+			)
+			if !assert.NoError(t, err) {
+				return
+			}
+			spec.Request.Header.Set("X-Request-Id", "req-1")
+			assert.NoError(t, goe2e.WithHMACSignature(tt.cfg)(spec.Request))
+			assert.Equal(t, tt.expected, spec.Request.Header.Get(tt.header))
+			if tt.cfg.TimestampHeader != "" {
+				assert.Equal(t, "1700000000", spec.Request.Header.Get(tt.cfg.TimestampHeader))
+			}
+		})
+	}
+}
+
+// Expected signatures are taken from the AWS Signature Version 4 test suite.
+func TestWithAWSSigV4(t *testing.T) {
+	cfg := goe2e.SigV4Config{
+		Credentials: goe2e.AWSCredentials{
+			AccessKeyID:     "AKIDEXAMPLE",
+			SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+		},
+		Region:  "us-east-1",
+		Service: "service",
+		Now: func() time.Time {
+			return time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+		},
+	}
+	testCases := []struct {
+		name      string
+		url       string
+		signature string
+	}{
+		{"get-vanilla", "https://example.amazonaws.com/", "5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"},
+		{"get-vanilla-query-order-key-case", "https://example.amazonaws.com/?Param2=value2&Param1=value1", "b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500"},
+		{"get-vanilla-query-order-value", "https://example.amazonaws.com/?Param1=value2&Param1=value1", "5772eed61e12b33fae39ee5e7012498b51d56abc0abb7c60486157bd471c4694"},
+		// not part of the suite: keys that are prefixes of other keys sort before them
+		{"prefix keys", "https://example.amazonaws.com/?a1=x&a-b=y&a=z", "ea6cc76d6ec7988e257aed113e41f1b62078e8f98e4d56e1eb9d974e30d3cf12"},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, err := goe2e.NewSpec(goe2e.WithUrl(tt.url))
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.NoError(t, goe2e.WithAWSSigV4(cfg)(spec.Request))
+			assert.Equal(t, "20150830T123600Z", spec.Request.Header.Get("X-Amz-Date"))
+			assert.Equal(t,
+				"AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature="+tt.signature,
+				spec.Request.Header.Get("Authorization"))
+		})
+	}
+
+	t.Run("S3 payload hash and session token", func(t *testing.T) {
+		s3 := cfg
+		s3.Service = "s3"
+		s3.ContentSHA256Header = true
+		s3.Credentials.SessionToken = "session-token"
+		spec, err := goe2e.NewSpec(
+			goe2e.WithMethod(http.MethodPut),
+			goe2e.WithUrl("http://localhost:9000/bucket/my file.txt"),
+			goe2e.WithBody([]byte("hello")),
+		)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.NoError(t, goe2e.WithAWSSigV4(s3)(spec.Request))
+		assert.Equal(t, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", spec.Request.Header.Get("X-Amz-Content-Sha256"))
+		assert.Equal(t, "session-token", spec.Request.Header.Get("X-Amz-Security-Token"))
+		assert.Contains(t, spec.Request.Header.Get("Authorization"), "SignedHeaders=host;x-amz-content-sha256;x-amz-date;x-amz-security-token,")
+	})
+}
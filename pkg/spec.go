@@ -12,6 +12,7 @@ type Spec struct {
 	Method  string
 	Url     string
 	Body    []byte
+	Header  http.Header
 	Request *http.Request
 }
 
@@ -23,6 +24,7 @@ func NewSpec(opts ...SpecOption) (*Spec, error) {
 		Method:  http.MethodGet,
 		Url:     "https://example.com",
 		Body:    make([]byte, 0),
+		Header:  http.Header{},
 		Request: nil,
 	}
 	for _, opt := range opts {
@@ -44,6 +46,9 @@ func (rs *Spec) generateRequest() error {
 	if err != nil {
 		return err
 	}
+	for k, v := range rs.Header {
+		req.Header[k] = v
+	}
 	rs.Request = req
 	return nil
 }
@@ -28,9 +28,18 @@ func WithUrl(url string) SpecOption {
 // WithBaseURLFromEnv attempts to build the request's url from a domain name and route.
 // The domain name literal is stored in a map called env, where the urlKey is the key under which it is stored.
 func WithBaseURLFromEnv(env H, urlKey string, route string) SpecOption {
+	return withBaseURLFromEnv(mapEnv(env), urlKey, route)
+}
+
+// WithBaseURLFromEnv is the Env counterpart of the WithBaseURLFromEnv function.
+func (e *Env) WithBaseURLFromEnv(urlKey string, route string) SpecOption {
+	return withBaseURLFromEnv(e, urlKey, route)
+}
+
+func withBaseURLFromEnv(store envStore, urlKey string, route string) SpecOption {
 	return func(rs *Spec) error {
-		baseUrl := ValueInMapByKey(urlKey, env)
-		if baseUrl == nil {
+		baseUrl, ok := store.lookup(urlKey)
+		if !ok {
 			return fmt.Errorf("baseURL not found: key %s not found in env", urlKey)
 		}
 		switch t := baseUrl.(type) {
@@ -68,13 +77,23 @@ func WithJSON(payload interface{}) SpecOption {
 // WithSetFromEnv tries to unmarshal the existing request body into a map and then set fields of the map to values from the passed env map.
 // Optionally takes a keymap to allow for different field naming in env and json body.
 func WithSetFromEnv(env H, keymap D) SpecOption {
+	return withSetFromEnv(mapEnv(env), keymap)
+}
+
+// WithSetFromEnv is the Env counterpart of the WithSetFromEnv function, setting the values of all keys visible from the scope it is called on.
+func (e *Env) WithSetFromEnv(keymap D) SpecOption {
+	return withSetFromEnv(e, keymap)
+}
+
+func withSetFromEnv(store envStore, keymap D) SpecOption {
 	return func(rs *Spec) error {
 		var body H
 		err := json.Unmarshal(rs.Body, &body)
 		if err != nil {
 			return fmt.Errorf("spec option WithSetFromEnv failed - json.Unmarshal: %s", err.Error())
 		}
-		for k, v := range env {
+		for _, k := range store.keys() {
+			v, _ := store.lookup(k)
 			keyInBody := k
 			if keymap != nil {
 				var ok bool
@@ -0,0 +1,304 @@
+package goe2e
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	ContentHeaderEventStream string = "text/event-stream"
+	// maxSSELineSize limits a single line of an event stream.
+	maxSSELineSize = 1 << 20
+)
+
+// SSEEvent is a single event of a text/event-stream. Event defaults to "message" like in browsers.
+// ID is the last event ID seen on the stream when the event was dispatched, it carries over to events without an id field.
+type SSEEvent struct {
+	ID    string
+	Event string
+	Data  string
+	Retry time.Duration
+}
+
+// SSEConfig defines when to stop reading a stream of Server-Sent Events.
+// Without MaxEvents and Window reading only stops when the server closes the stream.
+type SSEConfig struct {
+	// MaxEvents stops reading after the first n events, 0 means no limit.
+	MaxEvents int
+	// Window stops reading after the duration has passed, 0 means no limit.
+	Window time.Duration
+	// Reconnects is how often a stream closed by the server is reopened, sending the ID of the last event as Last-Event-ID.
+	// Waits for the retry interval of the stream, if the server set one.
+	Reconnects int
+}
+
+// SSEReader parses Server-Sent Events from a text/event-stream.
+type SSEReader struct {
+	scanner     *bufio.Scanner
+	first       bool
+	lastEventID string
+	retry       time.Duration
+}
+
+// NewSSEReader creates a SSEReader reading from r.
+func NewSSEReader(r io.Reader) *SSEReader {
+	sr := &SSEReader{}
+	sr.reset(r)
+	return sr
+}
+
+// reset continues parsing on a new stream, keeping the last event ID and retry interval.
+func (sr *SSEReader) reset(r io.Reader) {
+	sr.scanner = bufio.NewScanner(r)
+	sr.scanner.Buffer(make([]byte, 0, 4096), maxSSELineSize)
+	sr.scanner.Split(scanSSELines)
+	sr.first = true
+}
+
+// LastEventID returns the ID of the last event, which is sent as Last-Event-ID when reconnecting.
+func (sr *SSEReader) LastEventID() string {
+	return sr.lastEventID
+}
+
+// Retry returns the reconnection time set by the server, or 0.
+func (sr *SSEReader) Retry() time.Duration {
+	return sr.retry
+}
+
+// Next blocks until the next event is dispatched. Returns io.EOF at the end of the stream, discarding an incomplete event.
+func (sr *SSEReader) Next() (SSEEvent, error) {
+	var data strings.Builder
+	hasData := false
+	eventType := ""
+	for sr.scanner.Scan() {
+		line := sr.scanner.Text()
+		if sr.first {
+			line = strings.TrimPrefix(line, "\ufeff")
+			sr.first = false
+		}
+		if line == "" {
+			if !hasData {
+				eventType = ""
+				continue
+			}
+			if eventType == "" {
+				eventType = "message"
+			}
+			return SSEEvent{
+				ID:    sr.lastEventID,
+				Event: eventType,
+				Data:  strings.TrimSuffix(data.String(), "\n"),
+				Retry: sr.retry,
+			}, nil
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			eventType = value
+		case "data":
+			data.WriteString(value + "\n")
+			hasData = true
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				sr.lastEventID = value
+			}
+		case "retry":
+			if ms, err := strconv.Atoi(value); err == nil && strings.Trim(value, "0123456789") == "" {
+				sr.retry = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+	if err := sr.scanner.Err(); err != nil {
+		return SSEEvent{}, err
+	}
+	return SSEEvent{}, io.EOF
+}
+
+// scanSSELines splits a stream into lines ending with CRLF, LF or CR.
+func scanSSELines(data []byte, atEOF bool) (int, []byte, error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		if data[i] == '\n' {
+			return i + 1, data[:i], nil
+		}
+		if i+1 < len(data) {
+			if data[i+1] == '\n' {
+				return i + 2, data[:i], nil
+			}
+			return i + 1, data[:i], nil
+		}
+		if atEOF {
+			return i + 1, data[:i], nil
+		}
+		// a trailing CR might be followed by LF
+		return 0, nil, nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// WithLastEventID sets the Last-Event-ID header to resume an event stream after the given event.
+func WithLastEventID(id string) RequestModifier {
+	return func(r *http.Request) error {
+		r.Header.Set("Last-Event-ID", id)
+		return nil
+	}
+}
+
+type sseResult struct {
+	event SSEEvent
+	err   error
+}
+
+// ReadEvents reads Server-Sent Events from a stream opened by RunStream into Events until one of the limits of cfg is reached, then closes it.
+// The raw stream read is stored in the ResponseBody.
+func (rh *RequestHandler) ReadEvents(cfg SSEConfig) error {
+	if rh.Response == nil {
+		return fmt.Errorf("no stream opened before reading events, use RunStream")
+	}
+	var timeout <-chan time.Time
+	if cfg.Window > 0 {
+		timer := time.NewTimer(cfg.Window)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	var raw bytes.Buffer
+	defer func() {
+		rh.ResponseBody = append([]byte{}, raw.Bytes()...)
+	}()
+
+	reader := NewSSEReader(io.TeeReader(limitBody(rh.Response.Body, rh.maxResponseSize, 0), &raw))
+	for reconnects := 0; ; reconnects++ {
+		results := make(chan sseResult)
+		go func() {
+			defer close(results)
+			for {
+				ev, err := reader.Next()
+				results <- sseResult{event: ev, err: err}
+				if err != nil {
+					return
+				}
+			}
+		}()
+		ended, err := rh.collectEvents(results, cfg.MaxEvents, timeout)
+		rh.Response.Body.Close()
+		// wait for the reader to stop before touching the stream again
+		for range results {
+		}
+		if !ended {
+			return nil
+		}
+		if errors.Is(err, ErrBodyTooLarge) {
+			return fmt.Errorf("reading event stream failed: %w", err)
+		}
+		if reconnects >= cfg.Reconnects {
+			if err != nil && !errors.Is(err, io.EOF) {
+				return fmt.Errorf("reading event stream failed: %w", err)
+			}
+			return nil
+		}
+		if reader.Retry() > 0 {
+			select {
+			case <-time.After(reader.Retry()):
+			case <-timeout:
+				return nil
+			}
+		}
+		resp, err := rh.reconnect(reader.LastEventID())
+		if err != nil {
+			return fmt.Errorf("reconnecting to event stream failed: %w", err)
+		}
+		rh.Response = resp
+		// the server asks the client to stop reconnecting with any status but 200
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil
+		}
+		body, err := decodeBody(resp, resp.Body)
+		if err != nil {
+			resp.Body.Close()
+			return fmt.Errorf("reconnecting to event stream failed: %w", err)
+		}
+		resp.Body = body
+		reader.reset(io.TeeReader(limitBody(resp.Body, rh.maxResponseSize, int64(raw.Len())), &raw))
+	}
+}
+
+// collectEvents appends events to Events and reports if the stream ended before reaching a limit.
+func (rh *RequestHandler) collectEvents(results <-chan sseResult, maxEvents int, timeout <-chan time.Time) (bool, error) {
+	for {
+		select {
+		case res := <-results:
+			if res.err != nil {
+				return true, res.err
+			}
+			rh.Events = append(rh.Events, res.event)
+			if maxEvents > 0 && len(rh.Events) >= maxEvents {
+				return false, nil
+			}
+		case <-timeout:
+			return false, nil
+		}
+	}
+}
+
+// reconnect sends the request of the handler again, resuming after the last event.
+func (rh *RequestHandler) reconnect(lastEventID string) (*http.Response, error) {
+	r := rh.spec.Request.Clone(rh.spec.Request.Context())
+	if r.GetBody != nil {
+		body, err := r.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		r.Body = body
+	}
+	if lastEventID != "" {
+		r.Header.Set("Last-Event-ID", lastEventID)
+	}
+	return rh.send(r)
+}
+
+// TestSSEEventCount asserts the number of events read from the stream.
+func TestSSEEventCount(n int) func(*testing.T, *RequestHandler) {
+	return func(t *testing.T, rh *RequestHandler) {
+		assert.Len(t, rh.Events, n)
+	}
+}
+
+// TestSSEEvent asserts the event at index, comparing only the ID, Event and Data fields set in expected.
+func TestSSEEvent(index int, expected SSEEvent) func(*testing.T, *RequestHandler) {
+	return func(t *testing.T, rh *RequestHandler) {
+		if index >= len(rh.Events) {
+			assert.Fail(t, fmt.Sprintf("no event at index %d, read %d events", index, len(rh.Events)))
+			return
+		}
+		actual := rh.Events[index]
+		if expected.ID != "" {
+			assert.Equal(t, expected.ID, actual.ID, "id of event %d", index)
+		}
+		if expected.Event != "" {
+			assert.Equal(t, expected.Event, actual.Event, "type of event %d", index)
+		}
+		if expected.Data != "" {
+			assert.Equal(t, expected.Data, actual.Data, "data of event %d", index)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package goe2e_test
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	goe2e "github.com/J-Bockhofer/goe2e/pkg"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newSSEServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/events", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") != goe2e.ContentHeaderEventStream {
+			w.WriteHeader(http.StatusNotAcceptable)
+			return
+		}
+		w.Header().Set("Content-Type", goe2e.ContentHeaderEventStream)
+		switch r.Header.Get("Last-Event-ID") {
+		case "":
+			fmt.Fprint(w, "retry: 10\n\nid: 1\ndata: a\n\n: keep-alive\nid: 2\nevent: update\ndata: b\ndata: c\n\n")
+		case "2":
+			fmt.Fprint(w, "id: 3\ndata: d\n\n")
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+	// /gzip compresses every connection of the stream
+	mux.HandleFunc("/gzip", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", goe2e.ContentHeaderEventStream)
+		w.Header().Set("Content-Encoding", "gzip")
+		gw := gzip.NewWriter(w)
+		defer gw.Close()
+		if r.Header.Get("Last-Event-ID") == "" {
+			fmt.Fprint(gw, "retry: 10\nid: 1\ndata: a\n\n")
+			return
+		}
+		fmt.Fprint(gw, "id: 2\ndata: b\n\n")
+	})
+	mux.HandleFunc("/ticker", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", goe2e.ContentHeaderEventStream)
+		ticker := time.NewTicker(5 * time.Millisecond)
+		defer ticker.Stop()
+		for i := 0; ; i++ {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-ticker.C:
+				fmt.Fprintf(w, "id: %d\ndata: tick %d\n\n", i, i)
+				w.(http.Flusher).Flush()
+			}
+		}
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestSSE(t *testing.T) {
+	srv := newSSEServer()
+	defer srv.Close()
+
+	goe2e.TestRequest(t, &goe2e.TestConfig{
+		Name:     "GET /events with reconnect",
+		SpecOpts: []goe2e.SpecOption{goe2e.WithUrl(srv.URL + "/events")},
+		SSE:      &goe2e.SSEConfig{Reconnects: 2},
+		PostTestStatements: []goe2e.TestStatement{
+			{Description: "count", Statement: goe2e.TestSSEEventCount(3)},
+			{Description: "first", Statement: goe2e.TestSSEEvent(0, goe2e.SSEEvent{ID: "1", Event: "message", Data: "a"})},
+			{Description: "multi-line data", Statement: goe2e.TestSSEEvent(1, goe2e.SSEEvent{ID: "2", Event: "update", Data: "b\nc"})},
+			{Description: "resumed", Statement: goe2e.TestSSEEvent(2, goe2e.SSEEvent{ID: "3", Data: "d"})},
+			{Description: "retry", Statement: func(t *testing.T, rh *goe2e.RequestHandler) {
+				assert.Equal(t, 10*time.Millisecond, rh.Events[2].Retry)
+				assert.Equal(t, http.StatusNoContent, rh.Response.StatusCode)
+			}},
+			{Description: "raw body", Statement: func(t *testing.T, rh *goe2e.RequestHandler) {
+				assert.True(t, strings.HasSuffix(string(rh.ResponseBody), "id: 3\ndata: d\n\n"))
+			}},
+		},
+	})
+
+	goe2e.TestRequest(t, &goe2e.TestConfig{
+		Name:     "GET /events without reconnect",
+		SpecOpts: []goe2e.SpecOption{goe2e.WithUrl(srv.URL + "/events")},
+		SSE:      &goe2e.SSEConfig{},
+		PostTestStatements: []goe2e.TestStatement{
+			{Description: "count", Statement: goe2e.TestSSEEventCount(2)},
+		},
+	})
+
+	goe2e.TestRequest(t, &goe2e.TestConfig{
+		Name:        "GET /gzip with reconnect",
+		SpecOpts:    []goe2e.SpecOption{goe2e.WithUrl(srv.URL + "/gzip")},
+		RequestMods: []goe2e.RequestModifier{goe2e.WithAcceptEncoding(goe2e.EncodingGzip)},
+		SSE:         &goe2e.SSEConfig{Reconnects: 1},
+		PostTestStatements: []goe2e.TestStatement{
+			{Description: "count", Statement: goe2e.TestSSEEventCount(2)},
+			{Description: "resumed", Statement: goe2e.TestSSEEvent(1, goe2e.SSEEvent{ID: "2", Data: "b"})},
+		},
+	})
+
+	goe2e.TestRequest(t, &goe2e.TestConfig{
+		Name:     "GET /ticker first events",
+		SpecOpts: []goe2e.SpecOption{goe2e.WithUrl(srv.URL + "/ticker")},
+		SSE:      &goe2e.SSEConfig{MaxEvents: 3},
+		PostTestStatements: []goe2e.TestStatement{
+			{Description: "count", Statement: goe2e.TestSSEEventCount(3)},
+			{Description: "last", Statement: goe2e.TestSSEEvent(2, goe2e.SSEEvent{ID: "2", Data: "tick 2"})},
+		},
+	})
+
+	t.Run("Window", func(t *testing.T) {
+		start := time.Now()
+		rh, err := goe2e.RunConfig(&goe2e.TestConfig{
+			Name:     "GET /ticker window",
+			SpecOpts: []goe2e.SpecOption{goe2e.WithUrl(srv.URL + "/ticker")},
+			SSE:      &goe2e.SSEConfig{Window: 50 * time.Millisecond},
+		})
+		assert.NoError(t, err)
+		assert.Less(t, time.Since(start), time.Second)
+		assert.NotEmpty(t, rh.Events)
+	})
+
+	t.Run("Not streaming", func(t *testing.T) {
+		rh, err := goe2e.NewRequestHandler()
+		if assert.NoError(t, err) {
+			assert.Error(t, rh.ReadEvents(goe2e.SSEConfig{}))
+		}
+	})
+}
+
+func TestSSEReader(t *testing.T) {
+	testCases := []struct {
+		name     string
+		stream   string
+		expected []goe2e.SSEEvent
+	}{
+		{"Line endings", "\ufeffdata: a\r\n\r\ndata:b\r\rdata: c\n\n", []goe2e.SSEEvent{
+			{Event: "message", Data: "a"}, {Event: "message", Data: "b"}, {Event: "message", Data: "c"},
+		}},
+		{"Without data", "event: ping\n\ndata: x\n\n", []goe2e.SSEEvent{{Event: "message", Data: "x"}}},
+		{"Incomplete at end", "data: x\n\ndata: y", []goe2e.SSEEvent{{Event: "message", Data: "x"}}},
+		{"Id carries over", "id: 7\ndata: x\n\ndata: y\n\nid\ndata: z\n\n", []goe2e.SSEEvent{
+			{ID: "7", Event: "message", Data: "x"}, {ID: "7", Event: "message", Data: "y"}, {Event: "message", Data: "z"},
+		}},
+		{"Invalid retry", "retry: 1s\nretry: -5\ndata: x\n\n", []goe2e.SSEEvent{{Event: "message", Data: "x"}}},
+		{"Unknown field and empty data", "foo: bar\ndata\n\n", []goe2e.SSEEvent{{Event: "message", Data: ""}}},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			sr := goe2e.NewSSEReader(strings.NewReader(tt.stream))
+			var events []goe2e.SSEEvent
+			for {
+				ev, err := sr.Next()
+				if err == io.EOF {
+					break
+				}
+				if !assert.NoError(t, err) {
+					return
+				}
+				events = append(events, ev)
+			}
+			assert.Equal(t, tt.expected, events)
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package goe2e
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	ContentHeaderNDJSON string = "application/x-ndjson"
+	// streamChunkSize is the buffer size for reading a body in chunks.
+	streamChunkSize = 32 << 10
+)
+
+// StreamConfig defines how a response body is read as a stream of records, see ReadRecords.
+type StreamConfig struct {
+	// MaxRecords stops reading after the first n records, 0 means no limit.
+	MaxRecords int
+	// MaxBodySize fails reading with ErrBodyTooLarge once the body exceeds n bytes.
+	// 0 falls back to the limit set with WithMaxResponseSize, if any.
+	MaxBodySize int64
+}
+
+// RecordStatement is a named assertion run on every record of a streamed response, see TestConfig.Stream.
+type RecordStatement struct {
+	Description string
+	Statement   func(t *testing.T, index int, record []byte)
+}
+
+// ReadRecords reads newline-delimited records, like NDJSON, from a stream opened by RunStream and passes each to fn, then closes the stream.
+// Empty lines are skipped and line endings are stripped. Reading stops with the error returned by fn.
+// The records are not buffered, ResponseBody stays empty and only the count is stored in RecordCount.
+func (rh *RequestHandler) ReadRecords(cfg StreamConfig, fn func(index int, record []byte) error) error {
+	if rh.Response == nil {
+		return fmt.Errorf("no stream opened before reading records, use RunStream")
+	}
+	defer rh.Response.Body.Close()
+	rh.ResponseBody = []byte{}
+	rh.RecordCount = 0
+	br := bufio.NewReader(limitBody(rh.Response.Body, rh.bodyLimit(cfg.MaxBodySize), 0))
+	for cfg.MaxRecords == 0 || rh.RecordCount < cfg.MaxRecords {
+		line, err := br.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("reading record %d failed: %w", rh.RecordCount, err)
+		}
+		if record := bytes.TrimRight(line, "\r\n"); len(bytes.TrimSpace(record)) > 0 {
+			if fn != nil {
+				if fnErr := fn(rh.RecordCount, record); fnErr != nil {
+					return fmt.Errorf("record %d: %w", rh.RecordCount, fnErr)
+				}
+			}
+			rh.RecordCount++
+		}
+		if err != nil {
+			return nil
+		}
+	}
+	return nil
+}
+
+// ReadChunks reads a stream opened by RunStream in chunks of up to 32KB and passes each to fn, then closes the stream.
+// It is meant for large bodies that are not made of records. The chunk is only valid until fn returns.
+func (rh *RequestHandler) ReadChunks(cfg StreamConfig, fn func(chunk []byte) error) error {
+	if rh.Response == nil {
+		return fmt.Errorf("no stream opened before reading chunks, use RunStream")
+	}
+	defer rh.Response.Body.Close()
+	rh.ResponseBody = []byte{}
+	body := limitBody(rh.Response.Body, rh.bodyLimit(cfg.MaxBodySize), 0)
+	buf := make([]byte, streamChunkSize)
+	for {
+		n, err := body.Read(buf)
+		if n > 0 {
+			if fnErr := fn(buf[:n]); fnErr != nil {
+				return fnErr
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading stream failed: %w", err)
+		}
+	}
+}
+
+// StreamJSON reads a NDJSON stream opened by RunStream, decoding every record into a value of type T before passing it to fn.
+func StreamJSON[T any](rh *RequestHandler, cfg StreamConfig, fn func(index int, v T) error, opts ...DecodeOption) error {
+	return rh.ReadRecords(cfg, func(index int, record []byte) error {
+		v, err := decodeJSON[T](record, opts...)
+		if err != nil {
+			return err
+		}
+		return fn(index, v)
+	})
+}
+
+// TestRecordJSON decodes every streamed record into a value of type T and passes it to the statement.
+// The record fails without running the statement if it can not be decoded.
+func TestRecordJSON[T any](statement func(*testing.T, T), opts ...DecodeOption) func(*testing.T, int, []byte) {
+	return func(t *testing.T, index int, record []byte) {
+		v, err := decodeJSON[T](record, opts...)
+		if err != nil {
+			assert.Fail(t, fmt.Sprintf("record %d: %s", index, err.Error()))
+			return
+		}
+		statement(t, v)
+	}
+}
+
+// TestRecordCount asserts the number of records read from the stream.
+func TestRecordCount(n int) func(*testing.T, *RequestHandler) {
+	return func(t *testing.T, rh *RequestHandler) {
+		assert.Equal(t, n, rh.RecordCount)
+	}
+}
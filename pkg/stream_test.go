@@ -0,0 +1,147 @@
+package goe2e_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	goe2e "github.com/J-Bockhofer/goe2e/pkg"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newStreamServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/export", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") != goe2e.ContentHeaderNDJSON {
+			w.WriteHeader(http.StatusNotAcceptable)
+			return
+		}
+		w.Header().Set("Content-Type", goe2e.ContentHeaderNDJSON)
+		for i := 0; i < 100; i++ {
+			fmt.Fprintf(w, `{"name":"user%d","age":%d}`+"\r\n", i, 20+i%50)
+			if i%10 == 0 {
+				fmt.Fprint(w, "\n")
+				w.(http.Flusher).Flush()
+			}
+		}
+	})
+	mux.HandleFunc("/blob", func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < 10; i++ {
+			fmt.Fprint(w, strings.Repeat("x", 10000))
+			w.(http.Flusher).Flush()
+		}
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestStream(t *testing.T) {
+	srv := newStreamServer()
+	defer srv.Close()
+
+	goe2e.TestRequest(t, &goe2e.TestConfig{
+		Name:     "GET /export",
+		SpecOpts: []goe2e.SpecOption{goe2e.WithUrl(srv.URL + "/export")},
+		Stream:   &goe2e.StreamConfig{},
+		RecordStatements: []goe2e.RecordStatement{
+			{Description: "adult", Statement: goe2e.TestRecordJSON(func(t *testing.T, p person) {
+				assert.GreaterOrEqual(t, p.Age, 18)
+			}, goe2e.Strict())},
+			{Description: "named", Statement: func(t *testing.T, index int, record []byte) {
+				assert.Contains(t, string(record), fmt.Sprintf(`"user%d"`, index))
+			}},
+		},
+		PostTestStatements: []goe2e.TestStatement{
+			{Description: "status", Statement: goe2e.TestStatusCode(http.StatusOK)},
+			{Description: "count", Statement: goe2e.TestRecordCount(100)},
+		},
+	})
+
+	goe2e.TestRequest(t, &goe2e.TestConfig{
+		Name:     "GET /export first records",
+		SpecOpts: []goe2e.SpecOption{goe2e.WithUrl(srv.URL + "/export")},
+		Stream:   &goe2e.StreamConfig{MaxRecords: 5},
+		PostTestStatements: []goe2e.TestStatement{
+			{Description: "count", Statement: goe2e.TestRecordCount(5)},
+		},
+	})
+
+	t.Run("StreamJSON", func(t *testing.T) {
+		rh, err := goe2e.NewRequestHandler(goe2e.WithSpecOpts(goe2e.WithUrl(srv.URL + "/export")))
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.NoError(t, rh.ModifyRequest(goe2e.WithHeaders(goe2e.D{"Accept": goe2e.ContentHeaderNDJSON})))
+		if !assert.NoError(t, rh.RunStream()) {
+			return
+		}
+		total := 0
+		err = goe2e.StreamJSON(rh, goe2e.StreamConfig{}, func(index int, p person) error {
+			total += p.Age
+			if p.Name == "user42" {
+				return errors.New("stop")
+			}
+			return nil
+		})
+		assert.EqualError(t, err, "record 42: stop")
+		assert.Equal(t, 42, rh.RecordCount)
+		assert.Greater(t, total, 0)
+	})
+
+	t.Run("Max body size", func(t *testing.T) {
+		rh, err := goe2e.NewRequestHandler(goe2e.WithSpecOpts(goe2e.WithUrl(srv.URL + "/export")))
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.NoError(t, rh.ModifyRequest(goe2e.WithHeaders(goe2e.D{"Accept": goe2e.ContentHeaderNDJSON})))
+		if !assert.NoError(t, rh.RunStream()) {
+			return
+		}
+		err = rh.ReadRecords(goe2e.StreamConfig{MaxBodySize: 1000}, nil)
+		assert.ErrorIs(t, err, goe2e.ErrBodyTooLarge)
+		assert.ErrorContains(t, err, "exceeds limit of 1000 bytes")
+		assert.Less(t, rh.RecordCount, 100)
+	})
+
+	t.Run("Chunks", func(t *testing.T) {
+		testCases := []struct {
+			name    string
+			maxSize int64
+			read    int
+			err     bool
+		}{
+			{"No limit", 0, 100000, false},
+			{"Exact limit", 100000, 100000, false},
+			{"Over limit", 50000, 50000, true},
+		}
+		for _, tt := range testCases {
+			t.Run(tt.name, func(t *testing.T) {
+				rh, err := goe2e.NewRequestHandler(goe2e.WithSpecOpts(goe2e.WithUrl(srv.URL + "/blob")))
+				if !assert.NoError(t, err) || !assert.NoError(t, rh.RunStream()) {
+					return
+				}
+				read := 0
+				err = rh.ReadChunks(goe2e.StreamConfig{MaxBodySize: tt.maxSize}, func(chunk []byte) error {
+					read += len(chunk)
+					return nil
+				})
+				if tt.err {
+					assert.ErrorIs(t, err, goe2e.ErrBodyTooLarge)
+				} else {
+					assert.NoError(t, err)
+				}
+				assert.Equal(t, tt.read, read)
+			})
+		}
+	})
+
+	t.Run("Not streaming", func(t *testing.T) {
+		rh, err := goe2e.NewRequestHandler()
+		if assert.NoError(t, err) {
+			assert.Error(t, rh.ReadRecords(goe2e.StreamConfig{}, nil))
+		}
+	})
+}
@@ -1,6 +1,7 @@
 package goe2e
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 
@@ -11,11 +12,23 @@ import (
 type TestConfig struct {
 	// The name of the test.
 	Name string
+	// Tags classify the test, e.g. as TagDestructive. They are checked against the Profile before running.
+	Tags []string
+	// The Profile the test runs against. Optional, if set, tests with tags the profile does not allow are skipped.
+	Profile *Profile
 	// Request specific options, like url, method and body.
 	// After applying the options the http.Request will we constructed.
 	SpecOpts []SpecOption
+	// HandlerOpts configure the RequestHandler, like the client used to send the request. Applied after the SpecOpts.
+	HandlerOpts []RequestHandlerOption
 	// RequestMods are used to modify the http.Request, like the headers.
 	RequestMods []RequestModifier
+	// SSE reads the response as stream of Server-Sent Events into the handler's Events instead of reading the whole body.
+	SSE *SSEConfig
+	// Stream reads the response as newline-delimited records, like NDJSON, without buffering the body. Ignored if SSE is set.
+	Stream *StreamConfig
+	// RecordStatements are run on every record of a streamed response, reading stops at the first failing record.
+	RecordStatements []RecordStatement
 	// Can be thought of as a general pre-request script.
 	PreFunc RequestHandlerModifier
 	// Named test statements for running tests before sending the request.
@@ -45,26 +58,11 @@ func TestStatusCode(statusCode int) func(*testing.T, *RequestHandler) {
 // TestRequest is the main routine for running an E2E test as a unit test.
 // It executes the functions passed via the TestConfig with a fixed entry point for each of its field.
 func TestRequest(t *testing.T, tc *TestConfig) {
-	// create request, checking for nil pointer
-	rh, makeErr := NewRequestHandler(WithSpecOpts(tc.SpecOpts...))
-	if makeErr != nil {
-		t.Errorf("request: %s \nGenerating request failed: %s", tc.Name, makeErr.Error())
+	rh, ok := tc.setup(t, "request")
+	if !ok {
 		return
 	}
-	// run request modfications
-	modErr := rh.ModifyRequest(tc.RequestMods...)
-	if modErr != nil {
-		t.Errorf("request: %s \n%s", tc.Name, modErr.Error())
-		return
-	}
-	// run pre-flight "script"
-	if tc.PreFunc != nil {
-		preErr := tc.PreFunc.Apply(rh)
-		if preErr != nil {
-			t.Errorf("request: %s \nPre-request function failed: %s", tc.Name, preErr.Error())
-			return
-		}
-	}
+	defer rh.Close()
 	// pre-flight checks
 	for _, tt := range tc.PreTestStatements {
 		label := fmt.Sprintf("%s/[PRE]/%s", tc.Name, tt.Description)
@@ -72,37 +70,177 @@ func TestRequest(t *testing.T, tc *TestConfig) {
 			tt.Statement(t, rh)
 		})
 	}
-	// run request
-	runErr := rh.RunRequest()
-	if runErr != nil {
-		t.Errorf("request: %s \nRequest execution failed: %s", tc.Name, runErr.Error())
+	// run request, checking the records while they are streamed
+	var runErr error
+	if tc.Stream != nil && tc.SSE == nil && len(tc.RecordStatements) > 0 {
+		t.Run(fmt.Sprintf("%s/[RECORDS]", tc.Name), func(t *testing.T) {
+			runErr = tc.run(rh, tc.checkRecord(t))
+		})
+	} else {
+		runErr = tc.run(rh, nil)
+	}
+	if runErr != nil && !errors.Is(runErr, errRecordFailed) {
+		failf(t, "request: %s \nRequest execution failed: %s", tc.Name, runErr.Error())
 		return
 	}
 	// run response body modifications
 	modBodyErr := rh.ModifyResponseBody(tc.ResponseBodyMods...)
 	if modBodyErr != nil {
-		t.Errorf("request: %s \n%s\n", tc.Name, modBodyErr.Error())
+		failf(t, "request: %s \n%s\nresponse body: %s", tc.Name, modBodyErr.Error(), rh.BodyPreview())
 		return
 	}
 	// run response modfications
 	modRespErr := rh.ModifyResponse(tc.ResponseMods...)
 	if modRespErr != nil {
-		t.Errorf("request: %s \n%s", tc.Name, modRespErr.Error())
+		failf(t, "request: %s \n%s", tc.Name, modRespErr.Error())
 		return
 	}
 	// run post-flight "script"
 	if tc.PostFunc != nil {
 		postErr := tc.PostFunc.Apply(rh)
 		if postErr != nil {
-			t.Errorf("request: %s \nPost-request function failed: %s", tc.Name, postErr.Error())
+			failf(t, "request: %s \nPost-request function failed: %s", tc.Name, postErr.Error())
 			return
 		}
 	}
-	// post-flight checks
+	// post-flight checks, showing a preview of the body if any of them failed
+	failed := false
 	for _, tt := range tc.PostTestStatements {
 		label := fmt.Sprintf("%s/[POST]/%s", tc.Name, tt.Description)
-		t.Run(label, func(t *testing.T) {
+		if !t.Run(label, func(t *testing.T) {
 			tt.Statement(t, rh)
-		})
+		}) {
+			failed = true
+		}
+	}
+	if failed && len(rh.ResponseBody) > 0 {
+		t.Logf("request: %s response body:\n%s", tc.Name, rh.BodyPreview())
+	}
+}
+
+// RunConfig executes the request of a TestConfig, including all its modifiers and functions, but without the test statements.
+// It is meant for setup requests, like a login, whose results other tests depend on.
+// Like TestRequest it checks the profile guard, but fails instead of skipping a TestConfig the profile does not allow.
+func RunConfig(tc *TestConfig) (*RequestHandler, error) {
+	rh, err := tc.prepare()
+	if err != nil {
+		return nil, fmt.Errorf("request: %s - %w", tc.Name, err)
+	}
+	if err := tc.run(rh, nil); err != nil {
+		return nil, fmt.Errorf("request: %s - request execution failed: %w", tc.Name, err)
+	}
+	if err := rh.ModifyResponseBody(tc.ResponseBodyMods...); err != nil {
+		return nil, fmt.Errorf("request: %s - %w", tc.Name, err)
+	}
+	if err := rh.ModifyResponse(tc.ResponseMods...); err != nil {
+		return nil, fmt.Errorf("request: %s - %w", tc.Name, err)
+	}
+	if tc.PostFunc != nil {
+		if err := tc.PostFunc.Apply(rh); err != nil {
+			return nil, fmt.Errorf("request: %s - post-request function failed: %w", tc.Name, err)
+		}
+	}
+	return rh, nil
+}
+
+// profileGuardError is returned for a TestConfig its Profile does not allow, TestRequest skips it while RunConfig fails.
+type profileGuardError struct {
+	error
+}
+
+// prepare checks the profile guard before anything is sent, then builds the handler and applies the request modifiers and the PreFunc.
+// Credentials set by the modifiers are remembered, so they do not end up in the output.
+func (tc *TestConfig) prepare() (*RequestHandler, error) {
+	if tc.Profile != nil {
+		if err := tc.Profile.Allows(tc.Tags); err != nil {
+			return nil, profileGuardError{err}
+		}
 	}
+	rh, err := NewRequestHandler(tc.handlerOpts()...)
+	if err != nil {
+		return nil, fmt.Errorf("generating request failed: %w", err)
+	}
+	if err := rh.ModifyRequest(tc.RequestMods...); err != nil {
+		return nil, err
+	}
+	DefaultSecrets.AddFromHeader(rh.GetRequest().Header)
+	if tc.PreFunc != nil {
+		if err := tc.PreFunc.Apply(rh); err != nil {
+			return nil, fmt.Errorf("pre-request function failed: %w", err)
+		}
+	}
+	return rh, nil
+}
+
+// setup prepares the handler for a test routine, skipping the test if the profile does not allow it and failing it on any other error.
+// kind prefixes the messages, like "request" or "websocket".
+func (tc *TestConfig) setup(t *testing.T, kind string) (*RequestHandler, bool) {
+	rh, err := tc.prepare()
+	var guardErr profileGuardError
+	if errors.As(err, &guardErr) {
+		skipf(t, "%s: %s \n%s", kind, tc.Name, err.Error())
+		return nil, false
+	}
+	if err != nil {
+		failf(t, "%s: %s \n%s", kind, tc.Name, err.Error())
+		return nil, false
+	}
+	return rh, true
+}
+
+// errRecordFailed stops reading a stream after a record failed its statements.
+var errRecordFailed = errors.New("record statement failed")
+
+// run executes the request, reading the response as event stream or as records if configured.
+func (tc *TestConfig) run(rh *RequestHandler, onRecord func(int, []byte) error) error {
+	switch {
+	case tc.SSE != nil:
+		if rh.GetRequest().Header.Get("Accept") == "" {
+			rh.GetRequest().Header.Set("Accept", ContentHeaderEventStream)
+		}
+		if err := rh.RunStream(); err != nil {
+			return err
+		}
+		return rh.ReadEvents(*tc.SSE)
+	case tc.Stream != nil:
+		if rh.GetRequest().Header.Get("Accept") == "" {
+			rh.GetRequest().Header.Set("Accept", ContentHeaderNDJSON)
+		}
+		if err := rh.RunStream(); err != nil {
+			return err
+		}
+		return rh.ReadRecords(*tc.Stream, onRecord)
+	default:
+		return rh.RunRequest()
+	}
+}
+
+// checkRecord runs the RecordStatements on a record, stopping the stream once one of them fails.
+func (tc *TestConfig) checkRecord(t *testing.T) func(int, []byte) error {
+	return func(index int, record []byte) error {
+		for _, tt := range tc.RecordStatements {
+			tt.Statement(t, index, record)
+			if t.Failed() {
+				failf(t, "request: %s \nrecord %d failed statement: %s, stopped reading", tc.Name, index, tt.Description)
+				return errRecordFailed
+			}
+		}
+		return nil
+	}
+}
+
+func (tc *TestConfig) handlerOpts() []RequestHandlerOption {
+	return append([]RequestHandlerOption{WithSpecOpts(tc.SpecOpts...)}, tc.HandlerOpts...)
+}
+
+// failf reports a failure with all secrets redacted from the message.
+func failf(t *testing.T, format string, args ...any) {
+	t.Helper()
+	t.Error(DefaultSecrets.Redact(fmt.Sprintf(format, args...)))
+}
+
+// skipf skips the test with all secrets redacted from the message.
+func skipf(t *testing.T, format string, args ...any) {
+	t.Helper()
+	t.Skip(DefaultSecrets.Redact(fmt.Sprintf(format, args...)))
 }
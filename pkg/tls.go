@@ -0,0 +1,152 @@
+package goe2e
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// withTLSConfig modifies the TLS config of the handler's transport.
+func withTLSConfig(modify func(*tls.Config) error) RequestHandlerOption {
+	return WithTransportOption(func(t *http.Transport) error {
+		if t.TLSClientConfig == nil {
+			t.TLSClientConfig = &tls.Config{}
+		}
+		return modify(t.TLSClientConfig)
+	})
+}
+
+// WithCACert trusts the PEM encoded CA certificates in addition to the system roots, e.g. for a staging environment with an internal CA.
+func WithCACert(pem []byte) RequestHandlerOption {
+	return func(rh *RequestHandler) error {
+		// fail early on a broken bundle instead of on the first request
+		if !x509.NewCertPool().AppendCertsFromPEM(pem) {
+			return fmt.Errorf("handler option WithCACert failed: no PEM encoded certificate found")
+		}
+		return withTLSConfig(func(c *tls.Config) error {
+			pool := c.RootCAs
+			if pool == nil {
+				var err error
+				if pool, err = x509.SystemCertPool(); err != nil {
+					pool = x509.NewCertPool()
+				}
+			} else {
+				pool = pool.Clone()
+			}
+			pool.AppendCertsFromPEM(pem)
+			c.RootCAs = pool
+			return nil
+		})(rh)
+	}
+}
+
+// WithCABundle trusts the CA certificates of a PEM file in addition to the system roots, see WithCACert.
+func WithCABundle(path string) RequestHandlerOption {
+	return func(rh *RequestHandler) error {
+		pem, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("handler option WithCABundle failed - os.ReadFile: %w", err)
+		}
+		return WithCACert(pem)(rh)
+	}
+}
+
+// WithClientKeyPair presents the PEM encoded certificate to servers requiring mutual TLS.
+func WithClientKeyPair(certPEM, keyPEM []byte) RequestHandlerOption {
+	return func(rh *RequestHandler) error {
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return fmt.Errorf("handler option WithClientKeyPair failed - tls.X509KeyPair: %w", err)
+		}
+		return withTLSConfig(func(c *tls.Config) error {
+			c.Certificates = append(c.Certificates, cert)
+			return nil
+		})(rh)
+	}
+}
+
+// WithClientCertificate presents the certificate of the PEM files to servers requiring mutual TLS, see WithClientKeyPair.
+func WithClientCertificate(certFile, keyFile string) RequestHandlerOption {
+	return func(rh *RequestHandler) error {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return fmt.Errorf("handler option WithClientCertificate failed - tls.LoadX509KeyPair: %w", err)
+		}
+		return withTLSConfig(func(c *tls.Config) error {
+			c.Certificates = append(c.Certificates, cert)
+			return nil
+		})(rh)
+	}
+}
+
+// WithServerName sets the name sent via SNI and verified against the server's certificate, e.g. when connecting by IP address.
+func WithServerName(name string) RequestHandlerOption {
+	return withTLSConfig(func(c *tls.Config) error {
+		c.ServerName = name
+		return nil
+	})
+}
+
+// WithMinTLSVersion refuses connections with a protocol older than version, like tls.VersionTLS13.
+func WithMinTLSVersion(version uint16) RequestHandlerOption {
+	return withTLSConfig(func(c *tls.Config) error {
+		c.MinVersion = version
+		return nil
+	})
+}
+
+// CertificateFingerprint returns the SHA-256 fingerprint of the certificate as lowercase hex, as used by WithPinnedCertificates.
+func CertificateFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// WithPinnedCertificates only accepts connections whose certificate chain contains a certificate with one of the SHA-256 fingerprints.
+// Fingerprints are hex encoded and may contain colons, like the output of openssl x509 -fingerprint -sha256.
+// The pin is checked in addition to the regular verification.
+func WithPinnedCertificates(fingerprints ...string) RequestHandlerOption {
+	return func(rh *RequestHandler) error {
+		pins := map[string]bool{}
+		for _, fp := range fingerprints {
+			fp = strings.ToLower(strings.ReplaceAll(fp, ":", ""))
+			if b, err := hex.DecodeString(fp); err != nil || len(b) != sha256.Size {
+				return fmt.Errorf("handler option WithPinnedCertificates failed: %q is no SHA-256 fingerprint", fp)
+			}
+			pins[fp] = true
+		}
+		return withTLSConfig(func(c *tls.Config) error {
+			verify := c.VerifyConnection
+			c.VerifyConnection = func(cs tls.ConnectionState) error {
+				if verify != nil {
+					if err := verify(cs); err != nil {
+						return err
+					}
+				}
+				for _, cert := range cs.PeerCertificates {
+					if pins[CertificateFingerprint(cert)] {
+						return nil
+					}
+				}
+				return fmt.Errorf("certificate chain does not match any pinned fingerprint")
+			}
+			return nil
+		})(rh)
+	}
+}
+
+// WithInsecureSkipVerify accepts any certificate presented by the server, disabling protection against man-in-the-middle attacks.
+// Only meant for local setups with throw-away certificates, a warning is logged for each handler using it.
+// Pinned certificates are still checked.
+func WithInsecureSkipVerify() RequestHandlerOption {
+	return withTLSConfig(func(c *tls.Config) error {
+		slog.Warn("TLS certificate verification is disabled, connections are not protected against man-in-the-middle attacks")
+		c.InsecureSkipVerify = true
+		return nil
+	})
+}
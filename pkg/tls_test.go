@@ -0,0 +1,174 @@
+package goe2e_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	goe2e "github.com/J-Bockhofer/goe2e/pkg"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestCA creates a CA and a client certificate signed by it, returning the CA pool and the PEM encoded client key pair.
+func newTestCA(t *testing.T) (*x509.CertPool, []byte, []byte) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	caCert, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	clientKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	clientTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "e2e client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	clientDER, err := x509.CreateCertificate(rand.Reader, clientTemplate, caCert, &clientKey.PublicKey, caKey)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(clientKey)
+	require.NoError(t, err)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(caCert)
+	return pool,
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: clientDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func TestTLSOptions(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) > 0 {
+			w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+		}
+	}))
+	defer srv.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}), 0o600))
+	fingerprint := goe2e.CertificateFingerprint(srv.Certificate())
+
+	testCases := []struct {
+		name string
+		opts []goe2e.RequestHandlerOption
+		err  string
+	}{
+		{"Unknown CA", nil, "certificate signed by unknown authority"},
+		{"CA bundle", []goe2e.RequestHandlerOption{goe2e.WithCABundle(caFile)}, ""},
+		{"Server name", []goe2e.RequestHandlerOption{goe2e.WithCABundle(caFile), goe2e.WithServerName("example.com")}, ""},
+		{"Wrong server name", []goe2e.RequestHandlerOption{goe2e.WithCABundle(caFile), goe2e.WithServerName("other.test")}, "certificate is valid for"},
+		{"Pinned", []goe2e.RequestHandlerOption{goe2e.WithCABundle(caFile), goe2e.WithPinnedCertificates(fingerprint)}, ""},
+		{"Pin mismatch", []goe2e.RequestHandlerOption{goe2e.WithCABundle(caFile), goe2e.WithPinnedCertificates("AB:" + fingerprint[2:])}, "certificate chain does not match any pinned fingerprint"},
+		{"Insecure", []goe2e.RequestHandlerOption{goe2e.WithInsecureSkipVerify()}, ""},
+		{"Insecure still pinned", []goe2e.RequestHandlerOption{goe2e.WithInsecureSkipVerify(), goe2e.WithPinnedCertificates("00" + fingerprint[2:])}, "pinned fingerprint"},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			rh, err := goe2e.NewRequestHandler(append([]goe2e.RequestHandlerOption{goe2e.WithSpecOpts(goe2e.WithUrl(srv.URL))}, tt.opts...)...)
+			if !assert.NoError(t, err) {
+				return
+			}
+			err = rh.RunRequest()
+			if tt.err != "" {
+				assert.ErrorContains(t, err, tt.err)
+				return
+			}
+			if assert.NoError(t, err) {
+				assert.Equal(t, http.StatusOK, rh.Response.StatusCode)
+			}
+		})
+	}
+
+	t.Run("Shared client is not modified", func(t *testing.T) {
+		client := &http.Client{Transport: &http.Transport{}}
+		rh, err := goe2e.NewRequestHandler(goe2e.WithSpecOpts(goe2e.WithUrl(srv.URL)), goe2e.WithClient(client), goe2e.WithCABundle(caFile))
+		if assert.NoError(t, err) && assert.NoError(t, rh.RunRequest()) {
+			// Transport.Clone sets the HTTP/2 defaults of the original, but the trusted roots must stay untouched
+			if c := client.Transport.(*http.Transport).TLSClientConfig; c != nil {
+				assert.Nil(t, c.RootCAs)
+			}
+		}
+	})
+
+	t.Run("Unsupported transport", func(t *testing.T) {
+		client := &http.Client{Transport: goe2e.RoundTripperFunc(http.DefaultTransport.RoundTrip)}
+		rh, err := goe2e.NewRequestHandler(goe2e.WithSpecOpts(goe2e.WithUrl(srv.URL)), goe2e.WithClient(client), goe2e.WithInsecureSkipVerify())
+		if assert.NoError(t, err) {
+			assert.ErrorContains(t, rh.RunRequest(), "transport options require the client's transport to be a *http.Transport")
+		}
+	})
+
+	t.Run("Invalid options", func(t *testing.T) {
+		_, err := goe2e.NewRequestHandler(goe2e.WithCACert([]byte("no pem")))
+		assert.Error(t, err)
+		_, err = goe2e.NewRequestHandler(goe2e.WithCABundle(filepath.Join(t.TempDir(), "missing.pem")))
+		assert.Error(t, err)
+		_, err = goe2e.NewRequestHandler(goe2e.WithPinnedCertificates("abc"))
+		assert.Error(t, err)
+		_, err = goe2e.NewRequestHandler(goe2e.WithClientKeyPair([]byte("x"), []byte("y")))
+		assert.Error(t, err)
+	})
+}
+
+func TestMutualTLS(t *testing.T) {
+	clientCAs, certPEM, keyPEM := newTestCA(t)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	srv.StartTLS()
+	defer srv.Close()
+
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "client.pem"), filepath.Join(dir, "client.key")
+	require.NoError(t, os.WriteFile(certFile, certPEM, 0o600))
+	require.NoError(t, os.WriteFile(keyFile, keyPEM, 0o600))
+
+	goe2e.TestRequest(t, &goe2e.TestConfig{
+		Name:     "GET mTLS",
+		SpecOpts: []goe2e.SpecOption{goe2e.WithUrl(srv.URL)},
+		HandlerOpts: []goe2e.RequestHandlerOption{
+			goe2e.WithClient(srv.Client()),
+			goe2e.WithClientCertificate(certFile, keyFile),
+			goe2e.WithMinTLSVersion(tls.VersionTLS12),
+		},
+		PostTestStatements: []goe2e.TestStatement{
+			{Description: "client authenticated", Statement: func(t *testing.T, rh *goe2e.RequestHandler) {
+				assert.Equal(t, "e2e client", string(rh.ResponseBody))
+			}},
+		},
+	})
+
+	t.Run("Without client certificate", func(t *testing.T) {
+		rh, err := goe2e.NewRequestHandler(goe2e.WithSpecOpts(goe2e.WithUrl(srv.URL)), goe2e.WithClient(srv.Client()))
+		if assert.NoError(t, err) {
+			assert.Error(t, rh.RunRequest())
+		}
+	})
+}
@@ -0,0 +1,581 @@
+package goe2e
+
+import (
+	"bufio"
+	"bytes"
+	crand "crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	// wsGUID is appended to the handshake key to compute the accept header, see RFC 6455 section 1.3.
+	wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+	// maxWSMessageSize limits a single received message.
+	maxWSMessageSize = 32 << 20
+	// wsCloseTimeout is how long Close waits for the server to confirm the closing handshake.
+	wsCloseTimeout = time.Second
+)
+
+// WSMessageType is the opcode of a WebSocket message.
+type WSMessageType byte
+
+const (
+	WSText   WSMessageType = 1
+	WSBinary WSMessageType = 2
+	WSClose  WSMessageType = 8
+	wsPing   WSMessageType = 9
+	wsPong   WSMessageType = 10
+)
+
+func (mt WSMessageType) String() string {
+	switch mt {
+	case WSText:
+		return "text"
+	case WSBinary:
+		return "binary"
+	case WSClose:
+		return "close"
+	}
+	return fmt.Sprintf("opcode %d", byte(mt))
+}
+
+// WSMessage is a message sent or received on a WebSocket connection.
+type WSMessage struct {
+	// Sent is true for messages sent by the client.
+	Sent bool
+	Type WSMessageType
+	Data []byte
+	Time time.Time
+}
+
+// WSConn is the client side of a WebSocket connection, opened by (RequestHandler).DialWebSocket.
+// All sent and received messages are recorded in its transcript.
+type WSConn struct {
+	// Response is the response of the upgrade handshake.
+	Response *http.Response
+
+	rw       io.ReadWriteCloser
+	br       *bufio.Reader
+	writeMu  sync.Mutex
+	messages chan WSMessage
+	done     chan struct{}
+	readErr  error
+	start    time.Time
+
+	mu         sync.Mutex
+	transcript []WSMessage
+	closeSent  bool
+}
+
+// WithWebSocketProtocols requests the given subprotocols during the WebSocket handshake.
+func WithWebSocketProtocols(protocols ...string) RequestModifier {
+	return func(r *http.Request) error {
+		r.Header.Set("Sec-WebSocket-Protocol", strings.Join(protocols, ", "))
+		return nil
+	}
+}
+
+// DialWebSocket upgrades the request of the handler to a WebSocket connection.
+// The url may use the ws and wss schemes. The request is sent with the handler's client, including all its modifiers and wrappers,
+// so headers, cookies and authentication apply to the handshake like to any other request.
+func (rh *RequestHandler) DialWebSocket() (*WSConn, error) {
+	if rh.spec == nil {
+		return nil, fmt.Errorf("no request specifications initialized before executing")
+	}
+	if err := rh.ModifyRequest(); err != nil {
+		return nil, err
+	}
+	r := rh.spec.Request.Clone(rh.spec.Request.Context())
+	r.Method = http.MethodGet
+	r.Body = http.NoBody
+	r.ContentLength = 0
+	r.GetBody = nil
+	switch r.URL.Scheme {
+	case "ws":
+		r.URL.Scheme = "http"
+	case "wss":
+		r.URL.Scheme = "https"
+	}
+	keyBytes := make([]byte, 16)
+	if _, err := crand.Read(keyBytes); err != nil {
+		return nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(keyBytes)
+	r.Header.Set("Connection", "Upgrade")
+	r.Header.Set("Upgrade", "websocket")
+	r.Header.Set("Sec-WebSocket-Version", "13")
+	r.Header.Set("Sec-WebSocket-Key", key)
+
+	resp, err := rh.send(r)
+	if err != nil {
+		return nil, fmt.Errorf("websocket handshake failed: %w", err)
+	}
+	rh.Response = resp
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		resp.Body.Close()
+		return nil, fmt.Errorf("websocket handshake failed: status %d, expected %d", resp.StatusCode, http.StatusSwitchingProtocols)
+	}
+	if !strings.EqualFold(resp.Header.Get("Upgrade"), "websocket") {
+		resp.Body.Close()
+		return nil, fmt.Errorf("websocket handshake failed: upgrade header %q", resp.Header.Get("Upgrade"))
+	}
+	if resp.Header.Get("Sec-WebSocket-Accept") != wsAccept(key) {
+		resp.Body.Close()
+		return nil, fmt.Errorf("websocket handshake failed: invalid Sec-WebSocket-Accept")
+	}
+	rw, ok := resp.Body.(io.ReadWriteCloser)
+	if !ok {
+		resp.Body.Close()
+		return nil, fmt.Errorf("websocket handshake failed: connection is not writable")
+	}
+	c := &WSConn{
+		Response: resp,
+		rw:       rw,
+		br:       bufio.NewReader(rw),
+		messages: make(chan WSMessage, 64),
+		done:     make(chan struct{}),
+		start:    time.Now(),
+	}
+	go c.readLoop()
+	return c, nil
+}
+
+func wsAccept(key string) string {
+	h := sha1.Sum([]byte(key + wsGUID))
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+// Subprotocol returns the subprotocol selected by the server.
+func (c *WSConn) Subprotocol() string {
+	return c.Response.Header.Get("Sec-WebSocket-Protocol")
+}
+
+// SendText sends a text message.
+func (c *WSConn) SendText(s string) error {
+	return c.send(WSText, []byte(s))
+}
+
+// SendBinary sends a binary message.
+func (c *WSConn) SendBinary(b []byte) error {
+	return c.send(WSBinary, b)
+}
+
+// SendJSON marshals v and sends it as text message.
+func (c *WSConn) SendJSON(v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("sending websocket message failed - json.Marshal: %w", err)
+	}
+	return c.send(WSText, b)
+}
+
+func (c *WSConn) send(mt WSMessageType, data []byte) error {
+	if err := c.writeFrame(mt, data); err != nil {
+		return fmt.Errorf("sending websocket message failed: %w", err)
+	}
+	c.record(WSMessage{Sent: true, Type: mt, Data: data, Time: time.Now()})
+	return nil
+}
+
+// Receive waits for the next text or binary message.
+func (c *WSConn) Receive(timeout time.Duration) (WSMessage, error) {
+	return c.Await(WSAny(), timeout)
+}
+
+// Await waits for the next message matching m, skipping all others. Fails if none arrives within timeout or the connection closes.
+func (c *WSConn) Await(m WSMatcher, timeout time.Duration) (WSMessage, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case msg, ok := <-c.messages:
+			if !ok {
+				return WSMessage{}, fmt.Errorf("websocket closed while waiting for message: %w", c.readErr)
+			}
+			if m(msg) {
+				return msg, nil
+			}
+		case <-timer.C:
+			return WSMessage{}, fmt.Errorf("no matching websocket message within %s", timeout)
+		}
+	}
+}
+
+// Close performs the closing handshake and closes the connection.
+func (c *WSConn) Close() error {
+	c.mu.Lock()
+	sent := c.closeSent
+	c.closeSent = true
+	c.mu.Unlock()
+	if !sent {
+		payload := binary.BigEndian.AppendUint16(nil, 1000)
+		if err := c.writeFrame(WSClose, payload); err == nil {
+			c.record(WSMessage{Sent: true, Type: WSClose, Data: payload, Time: time.Now()})
+		}
+	}
+	select {
+	case <-c.done:
+	case <-time.After(wsCloseTimeout):
+	}
+	err := c.rw.Close()
+	// unblock the read loop if nobody consumed the remaining messages
+	for range c.messages {
+	}
+	<-c.done
+	return err
+}
+
+// Transcript returns all messages sent and received so far.
+func (c *WSConn) Transcript() []WSMessage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]WSMessage{}, c.transcript...)
+}
+
+// TranscriptString renders the transcript for reports, with the time since dialing, ">" for sent and "<" for received messages.
+// Secrets are redacted.
+func (c *WSConn) TranscriptString() string {
+	var b strings.Builder
+	for _, msg := range c.Transcript() {
+		dir := "<"
+		if msg.Sent {
+			dir = ">"
+		}
+		fmt.Fprintf(&b, "%8s %s %s: ", msg.Time.Sub(c.start).Round(time.Millisecond), dir, msg.Type)
+		switch msg.Type {
+		case WSText:
+			b.WriteString(string(msg.Data))
+		case WSClose:
+			if len(msg.Data) >= 2 {
+				fmt.Fprintf(&b, "%d %s", binary.BigEndian.Uint16(msg.Data), msg.Data[2:])
+			}
+		default:
+			fmt.Fprintf(&b, "%d bytes", len(msg.Data))
+		}
+		b.WriteString("\n")
+	}
+	return DefaultSecrets.Redact(b.String())
+}
+
+func (c *WSConn) record(msg WSMessage) {
+	c.mu.Lock()
+	c.transcript = append(c.transcript, msg)
+	c.mu.Unlock()
+}
+
+// readLoop assembles the received frames into messages, answers pings and the closing handshake.
+func (c *WSConn) readLoop() {
+	defer close(c.done)
+	defer close(c.messages)
+	var msgType WSMessageType
+	var buf []byte
+	for {
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			c.readErr = err
+			return
+		}
+		switch op {
+		case wsPing:
+			c.writeFrame(wsPong, payload)
+			continue
+		case wsPong:
+			continue
+		case WSClose:
+			c.record(WSMessage{Type: WSClose, Data: payload, Time: time.Now()})
+			c.mu.Lock()
+			sent := c.closeSent
+			c.closeSent = true
+			c.mu.Unlock()
+			if !sent {
+				code := payload
+				if len(code) > 2 {
+					code = code[:2]
+				}
+				c.writeFrame(WSClose, code)
+			}
+			c.readErr = io.EOF
+			return
+		case 0:
+			if msgType == 0 {
+				c.readErr = fmt.Errorf("unexpected continuation frame")
+				return
+			}
+		case WSText, WSBinary:
+			if msgType != 0 {
+				c.readErr = fmt.Errorf("new message before the previous one was finished")
+				return
+			}
+			msgType = op
+		default:
+			c.readErr = fmt.Errorf("unsupported opcode %d", op)
+			return
+		}
+		if len(buf)+len(payload) > maxWSMessageSize {
+			c.readErr = fmt.Errorf("message exceeds %d bytes", maxWSMessageSize)
+			return
+		}
+		buf = append(buf, payload...)
+		if !fin {
+			continue
+		}
+		msg := WSMessage{Type: msgType, Data: buf, Time: time.Now()}
+		c.record(msg)
+		c.messages <- msg
+		msgType, buf = 0, nil
+	}
+}
+
+// readFrame reads a single frame, which must not be masked when sent by a server.
+func (c *WSConn) readFrame() (bool, WSMessageType, []byte, error) {
+	return readWSFrame(c.br, false)
+}
+
+// writeFrame writes a single, final and masked frame.
+func (c *WSConn) writeFrame(op WSMessageType, payload []byte) error {
+	var mask [4]byte
+	if _, err := crand.Read(mask[:]); err != nil {
+		return err
+	}
+	frame := encodeWSFrame(op, payload, mask[:])
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	_, err := c.rw.Write(frame)
+	return err
+}
+
+// readWSFrame reads a single frame from r. Frames sent by a client have to be masked, those sent by a server must not be.
+func readWSFrame(r io.Reader, fromClient bool) (bool, WSMessageType, []byte, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		return false, 0, nil, err
+	}
+	if head[0]&0x70 != 0 {
+		return false, 0, nil, fmt.Errorf("reserved bits set without negotiated extension")
+	}
+	masked := head[1]&0x80 != 0
+	switch {
+	case masked && !fromClient:
+		return false, 0, nil, fmt.Errorf("masked frame from server")
+	case !masked && fromClient:
+		return false, 0, nil, fmt.Errorf("unmasked frame from client")
+	}
+	fin, op := head[0]&0x80 != 0, WSMessageType(head[0]&0x0f)
+	length := uint64(head[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	if length > maxWSMessageSize {
+		return false, 0, nil, fmt.Errorf("frame exceeds %d bytes", maxWSMessageSize)
+	}
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(r, mask[:]); err != nil {
+			return false, 0, nil, err
+		}
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return false, 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return fin, op, payload, nil
+}
+
+// encodeWSFrame encodes a single, final frame, masked with mask unless it is nil.
+func encodeWSFrame(op WSMessageType, payload []byte, mask []byte) []byte {
+	var frame bytes.Buffer
+	maskBit := byte(0)
+	if mask != nil {
+		maskBit = 0x80
+	}
+	frame.WriteByte(0x80 | byte(op))
+	switch n := len(payload); {
+	case n < 126:
+		frame.WriteByte(maskBit | byte(n))
+	case n <= 0xffff:
+		frame.WriteByte(maskBit | 126)
+		frame.Write(binary.BigEndian.AppendUint16(nil, uint16(n)))
+	default:
+		frame.WriteByte(maskBit | 127)
+		frame.Write(binary.BigEndian.AppendUint64(nil, uint64(n)))
+	}
+	if mask == nil {
+		frame.Write(payload)
+		return frame.Bytes()
+	}
+	frame.Write(mask)
+	for i, b := range payload {
+		frame.WriteByte(b ^ mask[i%4])
+	}
+	return frame.Bytes()
+}
+
+// WSMatcher selects the messages awaited by (WSConn).Await.
+type WSMatcher func(WSMessage) bool
+
+// WSAny matches any text or binary message.
+func WSAny() WSMatcher {
+	return func(msg WSMessage) bool {
+		return true
+	}
+}
+
+// WSTextEquals matches a text message with exactly the given content.
+func WSTextEquals(s string) WSMatcher {
+	return func(msg WSMessage) bool {
+		return msg.Type == WSText && string(msg.Data) == s
+	}
+}
+
+// WSTextContains matches a text message containing the given text.
+func WSTextContains(s string) WSMatcher {
+	return func(msg WSMessage) bool {
+		return msg.Type == WSText && strings.Contains(string(msg.Data), s)
+	}
+}
+
+// WSBinaryEquals matches a binary message with exactly the given content.
+func WSBinaryEquals(b []byte) WSMatcher {
+	return func(msg WSMessage) bool {
+		return msg.Type == WSBinary && bytes.Equal(msg.Data, b)
+	}
+}
+
+// WSJSONContains matches a text message holding a JSON object that has all keys of expected with equal values.
+func WSJSONContains(expected H) WSMatcher {
+	var want H
+	if b, err := json.Marshal(expected); err == nil {
+		json.Unmarshal(b, &want)
+	}
+	return func(msg WSMessage) bool {
+		if msg.Type != WSText {
+			return false
+		}
+		var got H
+		if err := json.Unmarshal(msg.Data, &got); err != nil {
+			return false
+		}
+		for k, v := range want {
+			if !reflect.DeepEqual(got[k], v) {
+				return false
+			}
+		}
+		return want != nil
+	}
+}
+
+// WSTestConfig is the TestConfig counterpart for WebSocket tests.
+// The handshake request is built like any other request, the statements then run in order on the open connection.
+type WSTestConfig struct {
+	// TestConfig describes the handshake request, only its Name, Tags, Profile, SpecOpts, like the ws:// or wss:// url,
+	// HandlerOpts, RequestMods and PreFunc are used.
+	TestConfig
+	// Named statements sending and awaiting messages, sharing the connection.
+	Statements []WSStatement
+}
+
+type WSStatement struct {
+	Description string
+	Statement   func(*testing.T, *WSConn)
+}
+
+// TestWebSocket is the main routine for running a WebSocket E2E test as a unit test.
+// The transcript of the connection is logged if any statement fails.
+func TestWebSocket(t *testing.T, wc *WSTestConfig) {
+	rh, ok := wc.setup(t, "websocket")
+	if !ok {
+		return
+	}
+	defer rh.Close()
+	conn, err := rh.DialWebSocket()
+	if err != nil {
+		failf(t, "websocket: %s \n%s", wc.Name, err.Error())
+		return
+	}
+	defer conn.Close()
+	failed := false
+	for _, tt := range wc.Statements {
+		label := fmt.Sprintf("%s/[WS]/%s", wc.Name, tt.Description)
+		if !t.Run(label, func(t *testing.T) {
+			tt.Statement(t, conn)
+		}) {
+			failed = true
+		}
+	}
+	if failed {
+		t.Logf("websocket: %s transcript:\n%s", wc.Name, conn.TranscriptString())
+	}
+}
+
+// WSSendText is a statement sending a text message.
+func WSSendText(s string) func(*testing.T, *WSConn) {
+	return func(t *testing.T, c *WSConn) {
+		assert.NoError(t, c.SendText(s))
+	}
+}
+
+// WSSendBinary is a statement sending a binary message.
+func WSSendBinary(b []byte) func(*testing.T, *WSConn) {
+	return func(t *testing.T, c *WSConn) {
+		assert.NoError(t, c.SendBinary(b))
+	}
+}
+
+// WSSendJSON is a statement sending v as JSON text message.
+func WSSendJSON(v any) func(*testing.T, *WSConn) {
+	return func(t *testing.T, c *WSConn) {
+		assert.NoError(t, c.SendJSON(v))
+	}
+}
+
+// WSExpect is a statement awaiting a message matching m within timeout.
+func WSExpect(m WSMatcher, timeout time.Duration) func(*testing.T, *WSConn) {
+	return func(t *testing.T, c *WSConn) {
+		_, err := c.Await(m, timeout)
+		assert.NoError(t, err)
+	}
+}
+
+// WSExpectClosed is a statement asserting that the server closes the connection within timeout.
+func WSExpectClosed(timeout time.Duration) func(*testing.T, *WSConn) {
+	return func(t *testing.T, c *WSConn) {
+		select {
+		case <-c.done:
+			if !errors.Is(c.readErr, io.EOF) {
+				assert.Fail(t, fmt.Sprintf("websocket closed without closing handshake: %s", c.readErr))
+			}
+		case <-time.After(timeout):
+			assert.Fail(t, fmt.Sprintf("websocket not closed within %s", timeout))
+		}
+	}
+}
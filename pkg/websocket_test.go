@@ -0,0 +1,277 @@
+package goe2e_test
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	goe2e "github.com/J-Bockhofer/goe2e/pkg"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/websocket"
+)
+
+// wsFrame is a single message of the test server, which runs on golang.org/x/net/websocket to check the client against an independent implementation.
+type wsFrame struct {
+	op   byte
+	data []byte
+}
+
+// wsFrameCodec sends and receives messages with their frame type, so the server can echo text as text and binary as binary.
+var wsFrameCodec = websocket.Codec{
+	Marshal: func(v any) ([]byte, byte, error) {
+		f := v.(wsFrame)
+		return f.data, f.op, nil
+	},
+	Unmarshal: func(data []byte, op byte, v any) error {
+		*v.(*wsFrame) = wsFrame{op: op, data: data}
+		return nil
+	},
+}
+
+func newWebSocketServer() *httptest.Server {
+	ws := websocket.Server{
+		// selects the subprotocol, the origin is not checked
+		Handshake: func(cfg *websocket.Config, r *http.Request) error {
+			if slices.Contains(cfg.Protocol, "chat.v2") {
+				cfg.Protocol = []string{"chat.v2"}
+			} else {
+				cfg.Protocol = nil
+			}
+			return nil
+		},
+		Handler: func(conn *websocket.Conn) {
+			send := func(op byte, data []byte) {
+				wsFrameCodec.Send(conn, wsFrame{op: op, data: data})
+			}
+			send(websocket.TextFrame, []byte(`{"type":"welcome","room":"`+conn.Request().URL.Query().Get("room")+`"}`))
+			for {
+				// a close frame of the client ends the loop, closing the connection answers it
+				var f wsFrame
+				if err := wsFrameCodec.Receive(conn, &f); err != nil {
+					return
+				}
+				text := f.op == websocket.TextFrame
+				switch {
+				case text && string(f.data) == "bye":
+					send(websocket.CloseFrame, append(binary.BigEndian.AppendUint16(nil, 1000), "see you"...))
+					return
+				case text && strings.HasPrefix(string(f.data), "{"):
+					send(websocket.TextFrame, []byte(`{"type":"ack","payload":`+string(f.data)+`}`))
+				case text && string(f.data) == "big":
+					send(websocket.BinaryFrame, bytes.Repeat([]byte{7}, 70000))
+				default:
+					send(f.op, f.data)
+				}
+			}
+		},
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer ws-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		ws.ServeHTTP(w, r)
+	}))
+}
+
+func TestWebSocket(t *testing.T) {
+	srv := newWebSocketServer()
+	defer srv.Close()
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/chat?room=lobby"
+
+	goe2e.TestWebSocket(t, &goe2e.WSTestConfig{
+		TestConfig: goe2e.TestConfig{
+			Name:     "WS /chat",
+			SpecOpts: []goe2e.SpecOption{goe2e.WithUrl(wsURL)},
+			RequestMods: []goe2e.RequestModifier{
+				goe2e.WithBearerToken("ws-token"),
+				goe2e.WithWebSocketProtocols("chat.v1", "chat.v2"),
+			},
+		},
+		Statements: []goe2e.WSStatement{
+			{Description: "subprotocol", Statement: func(t *testing.T, c *goe2e.WSConn) {
+				assert.Equal(t, "chat.v2", c.Subprotocol())
+			}},
+			{Description: "welcome", Statement: goe2e.WSExpect(goe2e.WSJSONContains(goe2e.H{"type": "welcome", "room": "lobby"}), time.Second)},
+			{Description: "send text", Statement: goe2e.WSSendText("hello")},
+			{Description: "echo", Statement: goe2e.WSExpect(goe2e.WSTextEquals("hello"), time.Second)},
+			{Description: "send binary", Statement: goe2e.WSSendBinary([]byte{1, 2, 3})},
+			{Description: "binary echo", Statement: goe2e.WSExpect(goe2e.WSBinaryEquals([]byte{1, 2, 3}), time.Second)},
+			{Description: "send json", Statement: goe2e.WSSendJSON(goe2e.H{"id": 1, "text": "hi"})},
+			{Description: "ack", Statement: goe2e.WSExpect(goe2e.WSJSONContains(goe2e.H{"type": "ack", "payload": goe2e.H{"id": 1, "text": "hi"}}), time.Second)},
+			{Description: "large message", Statement: func(t *testing.T, c *goe2e.WSConn) {
+				assert.NoError(t, c.SendText(strings.Repeat("x", 200)))
+				assert.NoError(t, c.SendText("big"))
+				msg, err := c.Await(func(msg goe2e.WSMessage) bool { return msg.Type == goe2e.WSBinary }, time.Second)
+				if assert.NoError(t, err) {
+					assert.Len(t, msg.Data, 70000)
+				}
+			}},
+			{Description: "timeout", Statement: func(t *testing.T, c *goe2e.WSConn) {
+				_, err := c.Await(goe2e.WSTextContains("never"), 20*time.Millisecond)
+				assert.Error(t, err)
+			}},
+			{Description: "server closes", Statement: func(t *testing.T, c *goe2e.WSConn) {
+				assert.NoError(t, c.SendText("bye"))
+				goe2e.WSExpectClosed(time.Second)(t, c)
+				transcript := c.TranscriptString()
+				assert.Contains(t, transcript, "> text: hello\n")
+				assert.Contains(t, transcript, "< close: 1000 see you\n")
+				assert.Contains(t, transcript, "< binary: 70000 bytes\n")
+			}},
+		},
+	})
+
+	t.Run("Handshake rejected", func(t *testing.T) {
+		rh, err := goe2e.NewRequestHandler(goe2e.WithSpecOpts(goe2e.WithUrl(wsURL)))
+		if !assert.NoError(t, err) {
+			return
+		}
+		_, err = rh.DialWebSocket()
+		assert.EqualError(t, err, "websocket handshake failed: status 401, expected 101")
+		assert.Equal(t, http.StatusUnauthorized, rh.Response.StatusCode)
+	})
+
+	t.Run("Timeout only limits the handshake", func(t *testing.T) {
+		rh, err := goe2e.NewRequestHandler(goe2e.WithSpecOpts(goe2e.WithUrl(wsURL)), goe2e.WithTimeout(50*time.Millisecond))
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.NoError(t, rh.ModifyRequest(goe2e.WithBearerToken("ws-token")))
+		c, err := rh.DialWebSocket()
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer c.Close()
+		goe2e.WSExpect(goe2e.WSJSONContains(goe2e.H{"type": "welcome"}), time.Second)(t, c)
+		time.Sleep(100 * time.Millisecond)
+		assert.NoError(t, c.SendText("still open"))
+		goe2e.WSExpect(goe2e.WSTextEquals("still open"), time.Second)(t, c)
+	})
+
+	t.Run("Client closes", func(t *testing.T) {
+		rh, err := goe2e.NewRequestHandler(goe2e.WithSpecOpts(goe2e.WithUrl(wsURL)))
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.NoError(t, rh.ModifyRequest(goe2e.WithBearerToken("ws-token")))
+		c, err := rh.DialWebSocket()
+		if !assert.NoError(t, err) {
+			return
+		}
+		_, err = c.Receive(time.Second)
+		assert.NoError(t, err)
+		assert.NoError(t, c.Close())
+		transcript := c.Transcript()
+		if assert.NotEmpty(t, transcript) {
+			last := transcript[len(transcript)-1]
+			assert.Equal(t, goe2e.WSClose, last.Type)
+		}
+	})
+}
+
+// rfcAccept computes the Sec-WebSocket-Accept value as specified in RFC 6455 section 4.2.2.
+func rfcAccept(key string) string {
+	h := sha1.Sum([]byte(key + "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"))
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+// rfcUnmask returns the payload of a masked frame with a payload of at most 125 bytes.
+func rfcUnmask(frame []byte) []byte {
+	mask, payload := frame[2:6], slices.Clone(frame[6:])
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return payload
+}
+
+// Frames are taken from the examples in RFC 6455 section 5.7.
+func TestWebSocketRFC6455Frames(t *testing.T) {
+	assert.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", rfcAccept("dGhlIHNhbXBsZSBub25jZQ=="))
+	assert.Equal(t, "Hello", string(rfcUnmask([]byte{0x81, 0x85, 0x37, 0xfa, 0x21, 0x3d, 0x7f, 0x9f, 0x4d, 0x51, 0x58})))
+
+	frames := [][]byte{
+		// a single-frame unmasked text message
+		{0x81, 0x05, 0x48, 0x65, 0x6c, 0x6c, 0x6f},
+		// a fragmented unmasked text message
+		{0x01, 0x03, 0x48, 0x65, 0x6c},
+		{0x80, 0x02, 0x6c, 0x6f},
+		// an unmasked ping, to be answered with a masked pong
+		{0x89, 0x05, 0x48, 0x65, 0x6c, 0x6c, 0x6f},
+		// 256 bytes binary message in a single unmasked frame
+		append([]byte{0x82, 0x7e, 0x01, 0x00}, bytes.Repeat([]byte{1}, 256)...),
+		// 64KiB binary message in a single unmasked frame
+		append([]byte{0x82, 0x7f, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00}, bytes.Repeat([]byte{2}, 65536)...),
+	}
+	received := make(chan []byte, 2)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, rw, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+		rw.WriteString("Sec-WebSocket-Accept: " + rfcAccept(r.Header.Get("Sec-WebSocket-Key")) + "\r\n\r\n")
+		for _, f := range frames {
+			rw.Write(f)
+		}
+		rw.Flush()
+		// the pong and the text message of the client
+		for range 2 {
+			head := make([]byte, 6)
+			if _, err := io.ReadFull(rw, head); err != nil {
+				return
+			}
+			payload := make([]byte, head[1]&0x7f)
+			if _, err := io.ReadFull(rw, payload); err != nil {
+				return
+			}
+			received <- append(head, payload...)
+		}
+	}))
+	defer srv.Close()
+
+	rh, err := goe2e.NewRequestHandler(goe2e.WithSpecOpts(goe2e.WithUrl("ws" + strings.TrimPrefix(srv.URL, "http"))))
+	if !assert.NoError(t, err) {
+		return
+	}
+	c, err := rh.DialWebSocket()
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer c.Close()
+	for _, expected := range []goe2e.WSMessage{
+		{Type: goe2e.WSText, Data: []byte("Hello")},
+		{Type: goe2e.WSText, Data: []byte("Hello")},
+		{Type: goe2e.WSBinary, Data: bytes.Repeat([]byte{1}, 256)},
+		{Type: goe2e.WSBinary, Data: bytes.Repeat([]byte{2}, 65536)},
+	} {
+		msg, err := c.Receive(time.Second)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, expected.Type, msg.Type)
+		assert.Equal(t, expected.Data, msg.Data)
+	}
+	assert.NoError(t, c.SendText("Hello"))
+
+	for _, opcode := range []byte{0x8a, 0x81} {
+		select {
+		case frame := <-received:
+			assert.Equal(t, []byte{opcode, 0x85}, frame[:2], "final frame with a masked payload of 5 bytes")
+			assert.Equal(t, "Hello", string(rfcUnmask(frame)))
+		case <-time.After(time.Second):
+			assert.Fail(t, "client frame not received")
+			return
+		}
+	}
+}
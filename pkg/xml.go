@@ -0,0 +1,508 @@
+package goe2e
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	ContentHeaderXML string = "application/xml"
+)
+
+// WithXML tries to marshal the given payload with encoding/xml and sets it, prefixed with the XML declaration, as the request body.
+// Also sets the matching Content-Type.
+func WithXML(payload interface{}) SpecOption {
+	return func(rs *Spec) error {
+		b, err := xml.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("spec option WithXML failed - xml.Marshal: %s", err.Error())
+		}
+		rs.Body = append([]byte(xml.Header), b...)
+		rs.Header.Set("Content-Type", ContentHeaderXML+"; charset=utf-8")
+		return nil
+	}
+}
+
+// ResponseXMLToEnv is the XML counterpart of ResponseJSONToEnv.
+// It writes the value of the first node matching the XPath expression in paths to each key defined in env.
+// If the env key is not found in paths, the expression //key is used, which finds the first element named like the key anywhere in the document.
+func ResponseXMLToEnv(env H, paths D) ResponseBodyModifier {
+	return responseXMLToEnv(mapEnv(env), paths)
+}
+
+// ResponseXMLToEnv is the Env counterpart of the ResponseXMLToEnv function, the values are set in the scope it is called on.
+func (e *Env) ResponseXMLToEnv(paths D) ResponseBodyModifier {
+	return responseXMLToEnv(e, paths)
+}
+
+func responseXMLToEnv(store envStore, paths D) ResponseBodyModifier {
+	return func(body []byte) ([]byte, error) {
+		doc, err := parseXML(body)
+		if err != nil {
+			return nil, err
+		}
+		for _, k := range store.keys() {
+			path, ok := paths[k]
+			if !ok {
+				path = "//" + k
+			}
+			values, err := doc.xpath(path)
+			if err != nil {
+				return nil, err
+			}
+			if len(values) == 0 {
+				continue
+			}
+			store.set(k, values[0], "response")
+		}
+		return body, nil
+	}
+}
+
+// XMLPath evaluates an XPath expression on the XML document and returns the string values of all matching nodes.
+// Supported is the abbreviated syntax with absolute (/a/b), relative (a/b) and descendant (//b) steps, the wildcard *,
+// a trailing @attr or text() step and the predicates [n], [@attr], [@attr='v'] and [child='v'].
+// Names are matched by their local name, namespace prefixes in the expression are ignored.
+func XMLPath(doc []byte, path string) ([]string, error) {
+	root, err := parseXML(doc)
+	if err != nil {
+		return nil, err
+	}
+	return root.xpath(path)
+}
+
+// TestXMLPath asserts that the first node matching the XPath expression in the XML response body has the expected value.
+// See XMLPath for the supported expressions.
+func TestXMLPath(path string, expected string) func(*testing.T, *RequestHandler) {
+	return func(t *testing.T, rh *RequestHandler) {
+		values, err := XMLPath(rh.ResponseBody, path)
+		if err != nil {
+			assert.Fail(t, err.Error())
+			return
+		}
+		if len(values) == 0 {
+			assert.Fail(t, fmt.Sprintf("no node matches %s in response body", path), "response body: %s", rh.BodyPreview())
+			return
+		}
+		assert.Equal(t, expected, values[0], "value at %s", path)
+	}
+}
+
+// TestXMLEqual asserts that the XML response body is equivalent to the expected document and shows a diff otherwise.
+// Attribute order, namespace prefixes, comments and whitespace between elements are ignored.
+func TestXMLEqual(expected string) func(*testing.T, *RequestHandler) {
+	return func(t *testing.T, rh *RequestHandler) {
+		want, err := parseXML([]byte(expected))
+		if err != nil {
+			assert.Fail(t, fmt.Sprintf("expected document: %s", err.Error()))
+			return
+		}
+		got, err := parseXML(rh.ResponseBody)
+		if err != nil {
+			assert.Fail(t, err.Error())
+			return
+		}
+		assert.Equal(t, want.canonical(), got.canonical())
+	}
+}
+
+// xmlNode is an element of a parsed XML document, or a text node if name.Local is empty.
+// The document itself is represented by an unnamed node holding the root element.
+type xmlNode struct {
+	name     xml.Name
+	attrs    []xml.Attr
+	text     string
+	children []*xmlNode
+	order    int
+}
+
+// parseXML reads an XML document into a tree of xmlNodes, dropping comments, directives and processing instructions.
+func parseXML(b []byte) (*xmlNode, error) {
+	doc := &xmlNode{}
+	stack := []*xmlNode{doc}
+	order := 0
+	d := xml.NewDecoder(bytes.NewReader(b))
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parsing xml failed: %w", err)
+		}
+		parent := stack[len(stack)-1]
+		order++
+		switch t := tok.(type) {
+		case xml.StartElement:
+			n := &xmlNode{name: t.Name, attrs: t.Copy().Attr, order: order}
+			parent.children = append(parent.children, n)
+			stack = append(stack, n)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if last := len(parent.children) - 1; last >= 0 && parent.children[last].isText() {
+				parent.children[last].text += string(t)
+				continue
+			}
+			parent.children = append(parent.children, &xmlNode{text: string(t), order: order})
+		}
+	}
+	if len(stack) != 1 || len(doc.elements()) != 1 {
+		return nil, fmt.Errorf("parsing xml failed: document needs exactly one root element")
+	}
+	return doc, nil
+}
+
+func (n *xmlNode) isText() bool {
+	return n.name.Local == ""
+}
+
+func (n *xmlNode) elements() []*xmlNode {
+	var elems []*xmlNode
+	for _, c := range n.children {
+		if !c.isText() {
+			elems = append(elems, c)
+		}
+	}
+	return elems
+}
+
+// value returns the concatenated text of the node and all its descendants.
+func (n *xmlNode) value() string {
+	if n.isText() {
+		return n.text
+	}
+	var b strings.Builder
+	for _, c := range n.children {
+		b.WriteString(c.value())
+	}
+	return b.String()
+}
+
+// descendantsOrSelf returns the node and all elements below it in document order.
+func (n *xmlNode) descendantsOrSelf() []*xmlNode {
+	nodes := []*xmlNode{n}
+	for _, c := range n.elements() {
+		nodes = append(nodes, c.descendantsOrSelf()...)
+	}
+	return nodes
+}
+
+func (n *xmlNode) attr(local string) (string, bool) {
+	for _, a := range n.attrs {
+		if a.Name.Local == local && a.Name.Space != "xmlns" {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
+
+// canonical renders the node as indented XML with sorted attributes, namespace URIs instead of prefixes and trimmed text.
+func (n *xmlNode) canonical() string {
+	var b strings.Builder
+	for _, c := range n.elements() {
+		c.writeCanonical(&b, 0)
+	}
+	return b.String()
+}
+
+func (n *xmlNode) writeCanonical(b *strings.Builder, depth int) {
+	indent := strings.Repeat("  ", depth)
+	b.WriteString(indent + "<" + canonicalName(n.name))
+	var attrs []string
+	for _, a := range n.attrs {
+		if a.Name.Space == "xmlns" || a.Name.Space == "" && a.Name.Local == "xmlns" {
+			continue
+		}
+		attrs = append(attrs, fmt.Sprintf("%s=%q", canonicalName(a.Name), a.Value))
+	}
+	sort.Strings(attrs)
+	for _, a := range attrs {
+		b.WriteString(" " + a)
+	}
+	b.WriteString(">")
+
+	var children []*xmlNode
+	for _, c := range n.children {
+		if c.isText() && strings.TrimSpace(c.text) == "" {
+			continue
+		}
+		children = append(children, c)
+	}
+	if len(children) == 1 && children[0].isText() {
+		b.WriteString(strings.TrimSpace(children[0].text))
+	} else if len(children) > 0 {
+		b.WriteString("\n")
+		for _, c := range children {
+			if c.isText() {
+				b.WriteString(indent + "  " + strings.TrimSpace(c.text) + "\n")
+				continue
+			}
+			c.writeCanonical(b, depth+1)
+		}
+		b.WriteString(indent)
+	}
+	b.WriteString("</" + canonicalName(n.name) + ">\n")
+}
+
+func canonicalName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return "{" + name.Space + "}" + name.Local
+}
+
+// xpathStep is a single location step of an XPath expression.
+type xpathStep struct {
+	descendant bool
+	name       string
+	preds      []xpathPred
+}
+
+// xpathPred is a predicate filtering the nodes selected by a step.
+type xpathPred struct {
+	index int
+	attr  bool
+	name  string
+	value *string
+}
+
+// xpath evaluates the expression on the document node.
+func (n *xmlNode) xpath(path string) ([]string, error) {
+	steps, err := parseXPath(path)
+	if err != nil {
+		return nil, err
+	}
+	nodes := []*xmlNode{n}
+	for i, step := range steps {
+		if strings.HasPrefix(step.name, "@") || step.name == "text()" {
+			if i != len(steps)-1 {
+				return nil, fmt.Errorf("invalid xpath %q: %s must be the last step", path, step.name)
+			}
+			return step.values(nodes), nil
+		}
+		nodes = step.apply(nodes)
+	}
+	var values []string
+	for _, node := range nodes {
+		values = append(values, strings.TrimSpace(node.value()))
+	}
+	return values, nil
+}
+
+// apply selects the matching children, or descendants, of the context nodes in document order.
+func (s xpathStep) apply(context []*xmlNode) []*xmlNode {
+	if s.descendant {
+		var expanded []*xmlNode
+		for _, n := range context {
+			expanded = append(expanded, n.descendantsOrSelf()...)
+		}
+		context = expanded
+	}
+	seen := map[*xmlNode]bool{}
+	var selected []*xmlNode
+	for _, n := range context {
+		var matches []*xmlNode
+		for _, c := range n.elements() {
+			if s.name == "*" || c.name.Local == s.name {
+				matches = append(matches, c)
+			}
+		}
+		for _, p := range s.preds {
+			matches = p.filter(matches)
+		}
+		for _, m := range matches {
+			if !seen[m] {
+				seen[m] = true
+				selected = append(selected, m)
+			}
+		}
+	}
+	sort.Slice(selected, func(i, j int) bool { return selected[i].order < selected[j].order })
+	return selected
+}
+
+// values reads the attribute or the direct text of the context nodes for a trailing @attr or text() step.
+func (s xpathStep) values(context []*xmlNode) []string {
+	if s.descendant {
+		var expanded []*xmlNode
+		for _, n := range context {
+			expanded = append(expanded, n.descendantsOrSelf()...)
+		}
+		context = expanded
+	}
+	var values []string
+	for _, n := range context {
+		if s.name == "text()" {
+			for _, c := range n.children {
+				if c.isText() && strings.TrimSpace(c.text) != "" {
+					values = append(values, strings.TrimSpace(c.text))
+				}
+			}
+			continue
+		}
+		if v, ok := n.attr(s.name[1:]); ok {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+func (p xpathPred) filter(nodes []*xmlNode) []*xmlNode {
+	if p.index > 0 {
+		if p.index > len(nodes) {
+			return nil
+		}
+		return nodes[p.index-1 : p.index]
+	}
+	var filtered []*xmlNode
+	for _, n := range nodes {
+		if p.matches(n) {
+			filtered = append(filtered, n)
+		}
+	}
+	return filtered
+}
+
+func (p xpathPred) matches(n *xmlNode) bool {
+	if p.attr {
+		v, ok := n.attr(p.name)
+		return ok && (p.value == nil || v == *p.value)
+	}
+	for _, c := range n.elements() {
+		if c.name.Local == p.name && (p.value == nil || strings.TrimSpace(c.value()) == *p.value) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseXPath splits an expression into its steps.
+func parseXPath(path string) ([]xpathStep, error) {
+	invalid := func(reason string) error {
+		return fmt.Errorf("invalid xpath %q: %s", path, reason)
+	}
+	rest := strings.TrimPrefix(path, "/")
+	if rest == "" {
+		return nil, invalid("empty path")
+	}
+	descendant := strings.HasPrefix(path, "//")
+	if descendant {
+		rest = rest[1:]
+	}
+	var steps []xpathStep
+	for {
+		end := stepEnd(rest)
+		if end == 0 {
+			return nil, invalid("empty step")
+		}
+		step, err := parseXPathStep(rest[:end], descendant)
+		if err != nil {
+			return nil, invalid(err.Error())
+		}
+		steps = append(steps, step)
+		rest = rest[end:]
+		if rest == "" {
+			break
+		}
+		descendant = strings.HasPrefix(rest, "//")
+		rest = strings.TrimPrefix(rest[1:], "/")
+		if rest == "" {
+			return nil, invalid("trailing slash")
+		}
+	}
+	return steps, nil
+}
+
+// stepEnd finds the slash ending the first step, skipping slashes in predicates.
+func stepEnd(s string) int {
+	depth, quote := 0, byte(0)
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+		case c == '/' && depth == 0:
+			return i
+		}
+	}
+	return len(s)
+}
+
+func parseXPathStep(s string, descendant bool) (xpathStep, error) {
+	name, preds, _ := strings.Cut(s, "[")
+	step := xpathStep{descendant: descendant, name: localName(name)}
+	if strings.HasPrefix(name, "@") {
+		step.name = "@" + localName(name[1:])
+	}
+	if step.name == "" || step.name == "@" {
+		return step, fmt.Errorf("empty step")
+	}
+	if preds == "" {
+		return step, nil
+	}
+	if step.name == "text()" || strings.HasPrefix(step.name, "@") {
+		return step, fmt.Errorf("predicates on %s are not supported", step.name)
+	}
+	for _, raw := range strings.Split(strings.TrimSuffix(preds, "]"), "][") {
+		pred, err := parseXPathPred(raw)
+		if err != nil {
+			return step, err
+		}
+		step.preds = append(step.preds, pred)
+	}
+	return step, nil
+}
+
+func parseXPathPred(s string) (xpathPred, error) {
+	s = strings.TrimSpace(s)
+	if i, err := strconv.Atoi(s); err == nil {
+		if i < 1 {
+			return xpathPred{}, fmt.Errorf("position %d out of range", i)
+		}
+		return xpathPred{index: i}, nil
+	}
+	pred := xpathPred{}
+	name, value, hasValue := strings.Cut(s, "=")
+	name = strings.TrimSpace(name)
+	if strings.HasPrefix(name, "@") {
+		pred.attr = true
+		name = name[1:]
+	}
+	pred.name = localName(name)
+	if pred.name == "" {
+		return pred, fmt.Errorf("invalid predicate [%s]", s)
+	}
+	if hasValue {
+		value = strings.TrimSpace(value)
+		if len(value) < 2 || value[0] != value[len(value)-1] || value[0] != '\'' && value[0] != '"' {
+			return pred, fmt.Errorf("predicate value in [%s] must be quoted", s)
+		}
+		value = value[1 : len(value)-1]
+		pred.value = &value
+	}
+	return pred, nil
+}
+
+// localName strips the namespace prefix of a name test.
+func localName(name string) string {
+	if _, local, ok := strings.Cut(name, ":"); ok {
+		return local
+	}
+	return name
+}
@@ -0,0 +1,145 @@
+package goe2e_test
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	goe2e "github.com/J-Bockhofer/goe2e/pkg"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const soapResponse = `<?xml version="1.0" encoding="UTF-8"?>
+<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/" xmlns:m="urn:orders">
+  <soap:Body>
+    <m:GetOrderResponse>
+      <m:Order id="42" status="shipped">
+        <m:Item sku="A-1"><m:Name>Pen</m:Name><m:Qty>2</m:Qty></m:Item>
+        <m:Item sku="B-2"><m:Name>Ink</m:Name><m:Qty>1</m:Qty></m:Item>
+        <!-- legacy -->
+        <m:Note>fragile <![CDATA[&]]> heavy</m:Note>
+      </m:Order>
+      <m:Token>abc</m:Token>
+    </m:GetOrderResponse>
+  </soap:Body>
+</soap:Envelope>`
+
+func TestXMLPath(t *testing.T) {
+	testCases := []struct {
+		name     string
+		path     string
+		expected []string
+		err      bool
+	}{
+		{"Absolute", "/Envelope/Body/GetOrderResponse/Token", []string{"abc"}, false},
+		{"Prefixed", "/soap:Envelope/soap:Body/m:GetOrderResponse/m:Token", []string{"abc"}, false},
+		{"Relative", "Envelope/Body/GetOrderResponse/Token", []string{"abc"}, false},
+		{"Descendant", "//Name", []string{"Pen", "Ink"}, false},
+		{"Nested descendant", "//Order//Qty", []string{"2", "1"}, false},
+		{"Wildcard", "//Item/*", []string{"Pen", "2", "Ink", "1"}, false},
+		{"Position", "//Item[2]/Name", []string{"Ink"}, false},
+		{"Attribute", "//Order/@status", []string{"shipped"}, false},
+		{"Descendant attribute", "//@sku", []string{"A-1", "B-2"}, false},
+		{"Attribute predicate", "//Item[@sku='B-2']/Qty", []string{"1"}, false},
+		{"Attribute exists", "//*[@id]/@id", []string{"42"}, false},
+		{"Child predicate", `//Item[Name="Pen"]/@sku`, []string{"A-1"}, false},
+		{"Multiple predicates", "//Item[@sku][2]/Name", []string{"Ink"}, false},
+		{"Text", "//Note/text()", []string{"fragile & heavy"}, false},
+		{"String value", "//Item[1]", []string{"Pen2"}, false},
+		{"No match", "//Missing", nil, false},
+		{"Position out of range", "//Item[3]", nil, false},
+		{"Empty", "", nil, true},
+		{"Trailing slash", "/Envelope/", nil, true},
+		{"Empty step", "/Envelope///Body", nil, true},
+		{"Attribute not last", "//@sku/Name", nil, true},
+		{"Unquoted value", "//Item[@sku=A-1]", nil, true},
+		{"Zero position", "//Item[0]", nil, true},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := goe2e.XMLPath([]byte(soapResponse), tt.path)
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, values)
+		})
+	}
+
+	t.Run("Invalid document", func(t *testing.T) {
+		_, err := goe2e.XMLPath([]byte("<a><b></a>"), "//b")
+		assert.Error(t, err)
+	})
+}
+
+type getOrder struct {
+	XMLName xml.Name `xml:"GetOrder"`
+	ID      int      `xml:"id,attr"`
+	Detail  bool     `xml:"Detail"`
+}
+
+func TestXMLRoundTrip(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req getOrder
+		b, _ := io.ReadAll(r.Body)
+		if r.Header.Get("Content-Type") != "application/xml; charset=utf-8" || xml.Unmarshal(b, &req) != nil || req.ID != 42 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", goe2e.ContentHeaderXML)
+		w.Write([]byte(soapResponse))
+	}))
+	defer srv.Close()
+
+	env := goe2e.NewEnv()
+	env.Set("Token", "")
+	env.Set("status", "")
+	env.Set("missing", "default")
+
+	goe2e.TestRequest(t, &goe2e.TestConfig{
+		Name: "POST /orders",
+		SpecOpts: []goe2e.SpecOption{
+			goe2e.WithMethod(http.MethodPost),
+			goe2e.WithUrl(srv.URL),
+			goe2e.WithXML(getOrder{ID: 42, Detail: true}),
+		},
+		ResponseBodyMods: []goe2e.ResponseBodyModifier{
+			env.ResponseXMLToEnv(goe2e.D{"status": "//Order/@status"}),
+		},
+		PostTestStatements: []goe2e.TestStatement{
+			{Description: "status 200", Statement: goe2e.TestStatusCode(http.StatusOK)},
+			{Description: "token", Statement: goe2e.TestXMLPath("//GetOrderResponse/Token", "abc")},
+			{Description: "equal document", Statement: goe2e.TestXMLEqual(`
+<env:Envelope xmlns:env="http://schemas.xmlsoap.org/soap/envelope/">
+  <env:Body>
+    <GetOrderResponse xmlns="urn:orders">
+      <Order status="shipped" id="42">
+        <Item sku="A-1"><Name>Pen</Name><Qty>2</Qty></Item>
+        <Item sku="B-2"><Name>Ink</Name><Qty>1</Qty></Item>
+        <Note>fragile &amp; heavy</Note>
+      </Order>
+      <Token>abc</Token>
+    </GetOrderResponse>
+  </env:Body>
+</env:Envelope>`)},
+		},
+	})
+
+	token, _ := env.String("Token")
+	status, _ := env.String("status")
+	missing, _ := env.String("missing")
+	assert.Equal(t, "abc", token)
+	assert.Equal(t, "shipped", status)
+	assert.Equal(t, "default", missing)
+
+	t.Run("Diff", func(t *testing.T) {
+		mock := &testing.T{}
+		rh := &goe2e.RequestHandler{ResponseBody: []byte(soapResponse)}
+		goe2e.TestXMLEqual(`<Envelope><Body/></Envelope>`)(mock, rh)
+		assert.True(t, mock.Failed())
+	})
+}